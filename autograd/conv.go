@@ -0,0 +1,190 @@
+package autograd
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Window describes a sliding window over images stored one per row in
+// channels-last order, so that the value of pixel (y, x) in channel c is
+// at column (y*Width+x)*Channels + c. Zero Padding pixels are added on
+// every side.
+type Window struct {
+	Height, Width, Channels   int // Input image
+	KernelHeight, KernelWidth int
+	Stride, Padding           int
+}
+
+// OutputSize returns the number of window positions along each axis
+func (w Window) OutputSize() (int, int) {
+	return (w.Height+2*w.Padding-w.KernelHeight)/w.Stride + 1,
+		(w.Width+2*w.Padding-w.KernelWidth)/w.Stride + 1
+}
+
+// check panics when a does not hold images of the window input size
+func (w Window) check(op string, a *Variable) {
+	_, cols := a.Dims()
+	if cols != w.Height*w.Width*w.Channels {
+		panic(fmt.Sprintf("autograd: %s of %d columns, expected %dx%dx%d images", op, cols, w.Height, w.Width, w.Channels))
+	}
+	if w.Stride <= 0 {
+		panic(fmt.Sprintf("autograd: %s with stride %d", op, w.Stride))
+	}
+}
+
+// visit calls f for every window position p of an image and every kernel
+// offset k inside it, with the input column of the pixel or -1 when it
+// falls in the padding. Kernel offsets are ordered (ky, kx).
+func (w Window) visit(f func(p, k, column int)) {
+	outH, outW := w.OutputSize()
+	for oy := 0; oy < outH; oy++ {
+		for ox := 0; ox < outW; ox++ {
+			p := oy*outW + ox
+			for ky := 0; ky < w.KernelHeight; ky++ {
+				for kx := 0; kx < w.KernelWidth; kx++ {
+					y := oy*w.Stride + ky - w.Padding
+					x := ox*w.Stride + kx - w.Padding
+					column := -1
+					if y >= 0 && y < w.Height && x >= 0 && x < w.Width {
+						column = (y*w.Width + x) * w.Channels
+					}
+					f(p, ky*w.KernelWidth+kx, column)
+				}
+			}
+		}
+	}
+}
+
+// Im2Col unrolls every window of a batch of images into a row, giving a
+// (samples*positions) x (KernelHeight*KernelWidth*Channels) matrix, so a
+// convolution becomes a product with a kernel matrix
+func Im2Col(a *Variable, w Window) *Variable {
+	w.check("Im2Col", a)
+	samples, _ := a.Dims()
+	outH, outW := w.OutputSize()
+	positions := outH * outW
+	patch := w.KernelHeight * w.KernelWidth * w.Channels
+
+	value := mat.NewDense(samples*positions, patch, nil)
+	for n := 0; n < samples; n++ {
+		image := a.Value.RawRowView(n)
+		w.visit(func(p, k, column int) {
+			if column >= 0 {
+				copy(value.RawRowView(n*positions + p)[k*w.Channels:(k+1)*w.Channels], image[column:column+w.Channels])
+			}
+		})
+	}
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		_, cols := a.Dims()
+		ga := mat.NewDense(samples, cols, nil)
+		for n := 0; n < samples; n++ {
+			image := ga.RawRowView(n)
+			w.visit(func(p, k, column int) {
+				if column >= 0 {
+					g := grad.RawRowView(n*positions + p)[k*w.Channels : (k+1)*w.Channels]
+					for c, v := range g {
+						image[column+c] += v
+					}
+				}
+			})
+		}
+		return []*mat.Dense{ga}
+	}, a)
+}
+
+// Reshape returns the values of a in the same row-major order with a new
+// shape
+func Reshape(a *Variable, rows, cols int) *Variable {
+	ar, ac := a.Dims()
+	if ar*ac != rows*cols {
+		panic(fmt.Sprintf("autograd: Reshape of %dx%d into %dx%d", ar, ac, rows, cols))
+	}
+	value := mat.NewDense(rows, cols, append([]float64(nil), a.Value.RawMatrix().Data...))
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		return []*mat.Dense{mat.NewDense(ar, ac, append([]float64(nil), grad.RawMatrix().Data...))}
+	}, a)
+}
+
+// MaxPool keeps the largest value of every channel in each window, giving
+// images of the window output size. Padding pixels never win.
+func MaxPool(a *Variable, w Window) *Variable {
+	w.check("MaxPool", a)
+	samples, _ := a.Dims()
+	outH, outW := w.OutputSize()
+	value := mat.NewDense(samples, outH*outW*w.Channels, nil)
+	argmax := make([]int, samples*outH*outW*w.Channels) // Input column of every output
+
+	for n := 0; n < samples; n++ {
+		image, out := a.Value.RawRowView(n), value.RawRowView(n)
+		for i := range out {
+			out[i] = math.Inf(-1)
+		}
+		w.visit(func(p, _, column int) {
+			if column < 0 {
+				return
+			}
+			for c := 0; c < w.Channels; c++ {
+				o := p*w.Channels + c
+				if image[column+c] > out[o] {
+					out[o] = image[column+c]
+					argmax[n*len(out)+o] = column + c
+				}
+			}
+		})
+	}
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		_, cols := a.Dims()
+		ga := mat.NewDense(samples, cols, nil)
+		for n := 0; n < samples; n++ {
+			image, g := ga.RawRowView(n), grad.RawRowView(n)
+			for o, v := range g {
+				image[argmax[n*len(g)+o]] += v
+			}
+		}
+		return []*mat.Dense{ga}
+	}, a)
+}
+
+// AvgPool averages every channel over each window, ignoring the padding
+func AvgPool(a *Variable, w Window) *Variable {
+	w.check("AvgPool", a)
+	samples, _ := a.Dims()
+	outH, outW := w.OutputSize()
+
+	// Number of real pixels in every window
+	counts := make([]float64, outH*outW)
+	w.visit(func(p, _, column int) {
+		if column >= 0 {
+			counts[p]++
+		}
+	})
+
+	value := mat.NewDense(samples, outH*outW*w.Channels, nil)
+	for n := 0; n < samples; n++ {
+		image, out := a.Value.RawRowView(n), value.RawRowView(n)
+		w.visit(func(p, _, column int) {
+			if column >= 0 {
+				for c := 0; c < w.Channels; c++ {
+					out[p*w.Channels+c] += image[column+c] / counts[p]
+				}
+			}
+		})
+	}
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		_, cols := a.Dims()
+		ga := mat.NewDense(samples, cols, nil)
+		for n := 0; n < samples; n++ {
+			image, g := ga.RawRowView(n), grad.RawRowView(n)
+			w.visit(func(p, _, column int) {
+				if column >= 0 {
+					for c := 0; c < w.Channels; c++ {
+						image[column+c] += g[p*w.Channels+c] / counts[p]
+					}
+				}
+			})
+		}
+		return []*mat.Dense{ga}
+	}, a)
+}
@@ -0,0 +1,275 @@
+package autograd
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// checkSameShape panics when a and b have different shapes
+func checkSameShape(op string, a, b *Variable) {
+	ar, ac := a.Dims()
+	br, bc := b.Dims()
+	if ar != br || ac != bc {
+		panic(fmt.Sprintf("autograd: %s of %dx%d and %dx%d", op, ar, ac, br, bc))
+	}
+}
+
+// Add returns a + b element-wise
+func Add(a, b *Variable) *Variable {
+	checkSameShape("Add", a, b)
+	var value mat.Dense
+	value.Add(a.Value, b.Value)
+	return newOp(&value, func(grad *mat.Dense) []*mat.Dense {
+		return []*mat.Dense{grad, grad}
+	}, a, b)
+}
+
+// Sub returns a - b element-wise
+func Sub(a, b *Variable) *Variable {
+	checkSameShape("Sub", a, b)
+	var value mat.Dense
+	value.Sub(a.Value, b.Value)
+	return newOp(&value, func(grad *mat.Dense) []*mat.Dense {
+		var gb mat.Dense
+		gb.Scale(-1, grad)
+		return []*mat.Dense{grad, &gb}
+	}, a, b)
+}
+
+// Mul returns the element-wise product of a and b
+func Mul(a, b *Variable) *Variable {
+	checkSameShape("Mul", a, b)
+	var value mat.Dense
+	value.MulElem(a.Value, b.Value)
+	return newOp(&value, func(grad *mat.Dense) []*mat.Dense {
+		var ga, gb mat.Dense
+		ga.MulElem(grad, b.Value)
+		gb.MulElem(grad, a.Value)
+		return []*mat.Dense{&ga, &gb}
+	}, a, b)
+}
+
+// Div returns the element-wise quotient a / b
+func Div(a, b *Variable) *Variable {
+	checkSameShape("Div", a, b)
+	var value mat.Dense
+	value.DivElem(a.Value, b.Value)
+	return newOp(&value, func(grad *mat.Dense) []*mat.Dense {
+		var ga, gb mat.Dense
+		ga.DivElem(grad, b.Value)
+		// d(a/b)/db = -(a/b)/b
+		gb.DivElem(&value, b.Value)
+		gb.MulElem(&gb, grad)
+		gb.Scale(-1, &gb)
+		return []*mat.Dense{&ga, &gb}
+	}, a, b)
+}
+
+// MatMul returns the matrix product a * b
+func MatMul(a, b *Variable) *Variable {
+	var value mat.Dense
+	value.Mul(a.Value, b.Value)
+	return newOp(&value, func(grad *mat.Dense) []*mat.Dense {
+		var ga, gb mat.Dense
+		if a.requiresGrad {
+			ga.Mul(grad, b.Value.T())
+		}
+		if b.requiresGrad {
+			gb.Mul(a.Value.T(), grad)
+		}
+		return []*mat.Dense{orNil(&ga, a), orNil(&gb, b)}
+	}, a, b)
+}
+
+// Transpose returns the transpose of a
+func Transpose(a *Variable) *Variable {
+	value := mat.DenseCopyOf(a.Value.T())
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		return []*mat.Dense{mat.DenseCopyOf(grad.T())}
+	}, a)
+}
+
+// Scale returns c * a
+func Scale(a *Variable, c float64) *Variable {
+	var value mat.Dense
+	value.Scale(c, a.Value)
+	return newOp(&value, func(grad *mat.Dense) []*mat.Dense {
+		var ga mat.Dense
+		ga.Scale(c, grad)
+		return []*mat.Dense{&ga}
+	}, a)
+}
+
+// AddScalar returns a + c element-wise
+func AddScalar(a *Variable, c float64) *Variable {
+	var value mat.Dense
+	value.Apply(func(_, _ int, v float64) float64 { return v + c }, a.Value)
+	return newOp(&value, func(grad *mat.Dense) []*mat.Dense {
+		return []*mat.Dense{grad}
+	}, a)
+}
+
+// Apply maps f over every element of a. df receives the input x and the
+// output y = f(x) and returns the derivative of f at x.
+func Apply(a *Variable, f func(x float64) float64, df func(x, y float64) float64) *Variable {
+	var value mat.Dense
+	value.Apply(func(_, _ int, x float64) float64 { return f(x) }, a.Value)
+	return newOp(&value, func(grad *mat.Dense) []*mat.Dense {
+		var ga mat.Dense
+		ga.Apply(func(i, j int, g float64) float64 {
+			return g * df(a.Value.At(i, j), value.At(i, j))
+		}, grad)
+		return []*mat.Dense{&ga}
+	}, a)
+}
+
+// Exp returns e^a element-wise
+func Exp(a *Variable) *Variable {
+	return Apply(a, math.Exp, func(_, y float64) float64 { return y })
+}
+
+// Log returns the natural logarithm of a element-wise
+func Log(a *Variable) *Variable {
+	return Apply(a, math.Log, func(x, _ float64) float64 { return 1 / x })
+}
+
+// Sqrt returns the square root of a element-wise
+func Sqrt(a *Variable) *Variable {
+	return Apply(a, math.Sqrt, func(_, y float64) float64 { return 0.5 / y })
+}
+
+// Square returns a^2 element-wise
+func Square(a *Variable) *Variable {
+	return Apply(a, func(x float64) float64 { return x * x }, func(x, _ float64) float64 { return 2 * x })
+}
+
+// Tanh returns the hyperbolic tangent of a element-wise
+func Tanh(a *Variable) *Variable {
+	return Apply(a, math.Tanh, func(_, y float64) float64 { return 1 - y*y })
+}
+
+// Sigmoid returns 1 / (1 + e^-a) element-wise
+func Sigmoid(a *Variable) *Variable {
+	return Apply(a, func(x float64) float64 { return 1 / (1 + math.Exp(-x)) },
+		func(_, y float64) float64 { return y * (1 - y) })
+}
+
+// Sum returns the sum of every element as a 1x1 Variable
+func Sum(a *Variable) *Variable {
+	value := mat.NewDense(1, 1, []float64{mat.Sum(a.Value)})
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		rows, cols := a.Dims()
+		g := grad.At(0, 0)
+		ga := mat.NewDense(rows, cols, nil)
+		ga.Apply(func(_, _ int, _ float64) float64 { return g }, ga)
+		return []*mat.Dense{ga}
+	}, a)
+}
+
+// Mean returns the mean of every element as a 1x1 Variable
+func Mean(a *Variable) *Variable {
+	rows, cols := a.Dims()
+	return Scale(Sum(a), 1/float64(rows*cols))
+}
+
+// ColumnSum returns the 1 x cols row of the sums of every column
+func ColumnSum(a *Variable) *Variable {
+	rows, cols := a.Dims()
+	value := mat.NewDense(1, cols, nil)
+	sums := value.RawRowView(0)
+	for i := 0; i < rows; i++ {
+		for j, v := range a.Value.RawRowView(i) {
+			sums[j] += v
+		}
+	}
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		return []*mat.Dense{repeatRow(grad.RawRowView(0), rows)}
+	}, a)
+}
+
+// ColumnMean returns the 1 x cols row of the means of every column
+func ColumnMean(a *Variable) *Variable {
+	rows, _ := a.Dims()
+	return Scale(ColumnSum(a), 1/float64(rows))
+}
+
+// BroadcastRows repeats the 1 x cols Variable row into rows x cols, for
+// instance to add a bias to every sample of a batch
+func BroadcastRows(row *Variable, rows int) *Variable {
+	if r, _ := row.Dims(); r != 1 {
+		panic(fmt.Sprintf("autograd: BroadcastRows of a variable with %d rows", r))
+	}
+	value := repeatRow(row.Value.RawRowView(0), rows)
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		_, cols := grad.Dims()
+		sums := mat.NewDense(1, cols, nil)
+		for i := 0; i < rows; i++ {
+			for j, g := range grad.RawRowView(i) {
+				sums.RawRowView(0)[j] += g
+			}
+		}
+		return []*mat.Dense{sums}
+	}, row)
+}
+
+// SliceCols returns the columns [from, to) of a
+func SliceCols(a *Variable, from, to int) *Variable {
+	rows, cols := a.Dims()
+	value := mat.DenseCopyOf(a.Value.Slice(0, rows, from, to))
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		ga := mat.NewDense(rows, cols, nil)
+		ga.Slice(0, rows, from, to).(*mat.Dense).Copy(grad)
+		return []*mat.Dense{ga}
+	}, a)
+}
+
+// ConcatCols joins Variables with the same number of rows side by side
+func ConcatCols(vars ...*Variable) *Variable {
+	rows, _ := vars[0].Dims()
+	total := 0
+	for _, v := range vars {
+		r, c := v.Dims()
+		if r != rows {
+			panic(fmt.Sprintf("autograd: ConcatCols of %d and %d rows", rows, r))
+		}
+		total += c
+	}
+	value := mat.NewDense(rows, total, nil)
+	offset := 0
+	for _, v := range vars {
+		_, c := v.Dims()
+		value.Slice(0, rows, offset, offset+c).(*mat.Dense).Copy(v.Value)
+		offset += c
+	}
+	return newOp(value, func(grad *mat.Dense) []*mat.Dense {
+		grads := make([]*mat.Dense, len(vars))
+		offset := 0
+		for i, v := range vars {
+			_, c := v.Dims()
+			if v.requiresGrad {
+				grads[i] = mat.DenseCopyOf(grad.Slice(0, rows, offset, offset+c))
+			}
+			offset += c
+		}
+		return grads
+	}, vars...)
+}
+
+// repeatRow builds a rows x len(row) matrix whose rows are copies of row
+func repeatRow(row []float64, rows int) *mat.Dense {
+	data := make([]float64, 0, rows*len(row))
+	for i := 0; i < rows; i++ {
+		data = append(data, row...)
+	}
+	return mat.NewDense(rows, len(row), data)
+}
+
+// orNil returns g when v requires a gradient and nil otherwise
+func orNil(g *mat.Dense, v *Variable) *mat.Dense {
+	if !v.requiresGrad {
+		return nil
+	}
+	return g
+}
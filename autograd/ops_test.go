@@ -0,0 +1,170 @@
+package autograd
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// randomMatrix returns a rows x cols matrix of values uniform in [min, max)
+func randomMatrix(rng *rand.Rand, rows, cols int, min, max float64) *mat.Dense {
+	m := mat.NewDense(rows, cols, nil)
+	m.Apply(func(_, _ int, _ float64) float64 { return min + (max-min)*rng.Float64() }, m)
+	return m
+}
+
+// checkGradients compares the gradients Backward computes for the inputs
+// of op with central finite differences. The output of op is reduced to
+// a scalar through a fixed random weighting, so every output element
+// contributes a different amount.
+func checkGradients(t *testing.T, name string, op func(vars ...*Variable) *Variable, inputs ...*mat.Dense) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	vars := make([]*Variable, len(inputs))
+	for i, input := range inputs {
+		vars[i] = NewVariable(input, true)
+	}
+	output := op(vars...)
+	rows, cols := output.Dims()
+	weights := randomMatrix(rng, rows, cols, -1, 1)
+	scalar := func() float64 {
+		return mat.Sum(mulElem(op(vars...).Value, weights))
+	}
+	Sum(Mul(output, Constant(weights))).Backward()
+
+	const epsilon = 1e-6
+	for i, v := range vars {
+		if v.Grad == nil {
+			t.Errorf("%s: input %d got no gradient", name, i)
+			continue
+		}
+		data := v.Value.RawMatrix().Data
+		for j := range data {
+			original := data[j]
+			data[j] = original + epsilon
+			plus := scalar()
+			data[j] = original - epsilon
+			minus := scalar()
+			data[j] = original
+
+			numeric := (plus - minus) / (2 * epsilon)
+			analytic := v.Grad.RawMatrix().Data[j]
+			if diff := math.Abs(analytic - numeric); diff > 1e-6*math.Max(1, math.Abs(numeric)) {
+				t.Errorf("%s: input %d element %d: analytic %v, numeric %v", name, i, j, analytic, numeric)
+			}
+		}
+	}
+}
+
+func mulElem(a, b *mat.Dense) *mat.Dense {
+	var m mat.Dense
+	m.MulElem(a, b)
+	return &m
+}
+
+func TestOpGradients(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	window := Window{Height: 4, Width: 5, Channels: 2, KernelHeight: 2, KernelWidth: 3, Stride: 1, Padding: 1}
+	pool := Window{Height: 4, Width: 4, Channels: 2, KernelHeight: 2, KernelWidth: 2, Stride: 2}
+
+	tests := []struct {
+		name   string
+		op     func(v ...*Variable) *Variable
+		inputs []*mat.Dense
+	}{
+		{"Add", func(v ...*Variable) *Variable { return Add(v[0], v[1]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1), randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Sub", func(v ...*Variable) *Variable { return Sub(v[0], v[1]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1), randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Mul", func(v ...*Variable) *Variable { return Mul(v[0], v[1]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1), randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Div", func(v ...*Variable) *Variable { return Div(v[0], v[1]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1), randomMatrix(rng, 3, 4, 0.5, 2)}},
+		{"MatMul", func(v ...*Variable) *Variable { return MatMul(v[0], v[1]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1), randomMatrix(rng, 4, 2, -1, 1)}},
+		{"Transpose", func(v ...*Variable) *Variable { return Transpose(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Scale", func(v ...*Variable) *Variable { return Scale(v[0], -2.5) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"AddScalar", func(v ...*Variable) *Variable { return AddScalar(v[0], 3) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Apply", func(v ...*Variable) *Variable {
+			return Apply(v[0], func(x float64) float64 { return x * x * x }, func(x, _ float64) float64 { return 3 * x * x })
+		}, []*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Exp", func(v ...*Variable) *Variable { return Exp(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Log", func(v ...*Variable) *Variable { return Log(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, 0.5, 2)}},
+		{"Sqrt", func(v ...*Variable) *Variable { return Sqrt(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, 0.5, 2)}},
+		{"Square", func(v ...*Variable) *Variable { return Square(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Tanh", func(v ...*Variable) *Variable { return Tanh(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -2, 2)}},
+		{"Sigmoid", func(v ...*Variable) *Variable { return Sigmoid(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -2, 2)}},
+		{"Sum", func(v ...*Variable) *Variable { return Sum(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"Mean", func(v ...*Variable) *Variable { return Mean(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"ColumnSum", func(v ...*Variable) *Variable { return ColumnSum(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"ColumnMean", func(v ...*Variable) *Variable { return ColumnMean(v[0]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"BroadcastRows", func(v ...*Variable) *Variable { return BroadcastRows(v[0], 5) },
+			[]*mat.Dense{randomMatrix(rng, 1, 4, -1, 1)}},
+		{"SliceCols", func(v ...*Variable) *Variable { return SliceCols(v[0], 1, 3) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"ConcatCols", func(v ...*Variable) *Variable { return ConcatCols(v[0], v[1], v[2]) },
+			[]*mat.Dense{randomMatrix(rng, 3, 2, -1, 1), randomMatrix(rng, 3, 1, -1, 1), randomMatrix(rng, 3, 3, -1, 1)}},
+		{"Im2Col", func(v ...*Variable) *Variable { return Im2Col(v[0], window) },
+			[]*mat.Dense{randomMatrix(rng, 2, 4*5*2, -1, 1)}},
+		{"Reshape", func(v ...*Variable) *Variable { return Reshape(v[0], 6, 2) },
+			[]*mat.Dense{randomMatrix(rng, 3, 4, -1, 1)}},
+		{"MaxPool", func(v ...*Variable) *Variable { return MaxPool(v[0], pool) },
+			[]*mat.Dense{randomMatrix(rng, 2, 4*4*2, -1, 1)}},
+		{"AvgPool", func(v ...*Variable) *Variable { return AvgPool(v[0], pool) },
+			[]*mat.Dense{randomMatrix(rng, 2, 4*4*2, -1, 1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkGradients(t, tt.name, tt.op, tt.inputs...)
+		})
+	}
+}
+
+func TestBackwardAccumulatesReusedVariable(t *testing.T) {
+	x := NewVariable(mat.NewDense(1, 2, []float64{2, 3}), true)
+	// y = x*x + 3x, so dy/dx = 2x + 3
+	y := Add(Mul(x, x), Scale(x, 3))
+	y.BackwardWith(mat.NewDense(1, 2, []float64{1, 1}))
+	if got := x.Grad.RawRowView(0); got[0] != 7 || got[1] != 9 {
+		t.Fatalf("got gradient %v, expected [7 9]", got)
+	}
+
+	// A second call adds to the first
+	y.BackwardWith(mat.NewDense(1, 2, []float64{1, 1}))
+	if got := x.Grad.RawRowView(0); got[0] != 14 || got[1] != 18 {
+		t.Fatalf("got accumulated gradient %v, expected [14 18]", got)
+	}
+
+	x.ZeroGrad()
+	Sum(y).Backward()
+	if got := x.Grad.RawRowView(0); got[0] != 7 || got[1] != 9 {
+		t.Fatalf("got gradient %v after ZeroGrad, expected [7 9]", got)
+	}
+}
+
+func TestConstantGetsNoGradient(t *testing.T) {
+	x := NewVariable(mat.NewDense(1, 1, []float64{2}), true)
+	c := Constant(mat.NewDense(1, 1, []float64{5}))
+	Mul(x, c).Backward()
+	if c.Grad != nil {
+		t.Errorf("constant got gradient %v", c.Grad)
+	}
+	if x.Grad.At(0, 0) != 5 {
+		t.Errorf("got gradient %v, expected 5", x.Grad.At(0, 0))
+	}
+}
@@ -0,0 +1,145 @@
+// Package autograd implements reverse-mode automatic differentiation on
+// gonum matrices. Operations on Variables record a computation graph, and
+// Backward walks it from an output to accumulate the gradient of every
+// leaf Variable that requires one.
+package autograd
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Variable is a node of the computation graph holding a matrix value.
+// Leaves are created with NewVariable or Constant; every operation returns
+// a new Variable remembering its inputs and how to propagate gradients to
+// them.
+type Variable struct {
+	Value *mat.Dense
+	Grad  *mat.Dense // Accumulated gradient of a leaf, nil before the first Backward
+
+	requiresGrad bool
+	parents      []*Variable
+
+	// backward maps the gradient of this node to the gradients of its
+	// parents, returning nil for the parents that do not require one
+	backward func(grad *mat.Dense) []*mat.Dense
+}
+
+// NewVariable creates a leaf holding value. With requiresGrad the gradients
+// of Backward calls are accumulated into its Grad. The value is not copied.
+func NewVariable(value *mat.Dense, requiresGrad bool) *Variable {
+	return &Variable{Value: value, requiresGrad: requiresGrad}
+}
+
+// Constant creates a leaf that never receives a gradient
+func Constant(value *mat.Dense) *Variable {
+	return NewVariable(value, false)
+}
+
+// RequiresGrad reports whether gradients flow into this Variable
+func (v *Variable) RequiresGrad() bool {
+	return v.requiresGrad
+}
+
+// Dims returns the shape of the value
+func (v *Variable) Dims() (int, int) {
+	return v.Value.Dims()
+}
+
+// ZeroGrad clears the accumulated gradient
+func (v *Variable) ZeroGrad() {
+	v.Grad = nil
+}
+
+// newOp creates the result of an operation, which requires a gradient
+// when any of its parents does
+func newOp(value *mat.Dense, backward func(grad *mat.Dense) []*mat.Dense, parents ...*Variable) *Variable {
+	v := &Variable{Value: value}
+	for _, p := range parents {
+		if p.requiresGrad {
+			v.requiresGrad = true
+			break
+		}
+	}
+	if v.requiresGrad {
+		v.parents = parents
+		v.backward = backward
+	}
+	return v
+}
+
+// Backward computes the gradients of a 1x1 Variable, such as a loss, with
+// respect to every leaf it depends on
+func (v *Variable) Backward() {
+	rows, cols := v.Dims()
+	if rows != 1 || cols != 1 {
+		panic(fmt.Sprintf("autograd: Backward needs a 1x1 variable, got %dx%d; use BackwardWith", rows, cols))
+	}
+	v.BackwardWith(mat.NewDense(1, 1, []float64{1}))
+}
+
+// BackwardWith propagates grad, the gradient of some scalar with respect
+// to this Variable, through the graph. The gradients reaching each leaf
+// are added to its Grad, so several calls accumulate; intermediate
+// results keep no gradient.
+func (v *Variable) BackwardWith(grad *mat.Dense) {
+	rows, cols := v.Dims()
+	if r, c := grad.Dims(); r != rows || c != cols {
+		panic(fmt.Sprintf("autograd: gradient is %dx%d, variable is %dx%d", r, c, rows, cols))
+	}
+	if !v.requiresGrad {
+		return
+	}
+
+	grads := map[*Variable]*mat.Dense{v: mat.DenseCopyOf(grad)}
+	order := v.topologicalOrder()
+	for i := len(order) - 1; i >= 0; i-- {
+		node := order[i]
+		g := grads[node]
+		delete(grads, node)
+		if g == nil {
+			continue
+		}
+		if node.backward == nil {
+			accumulate(&node.Grad, g)
+			continue
+		}
+		for j, pg := range node.backward(g) {
+			if pg != nil && node.parents[j].requiresGrad {
+				parentGrad := grads[node.parents[j]]
+				accumulate(&parentGrad, pg)
+				grads[node.parents[j]] = parentGrad
+			}
+		}
+	}
+}
+
+// topologicalOrder lists the nodes requiring a gradient so that every node
+// comes after its parents
+func (v *Variable) topologicalOrder() []*Variable {
+	var order []*Variable
+	visited := map[*Variable]bool{}
+	var visit func(node *Variable)
+	visit = func(node *Variable) {
+		if visited[node] || !node.requiresGrad {
+			return
+		}
+		visited[node] = true
+		for _, p := range node.parents {
+			visit(p)
+		}
+		order = append(order, node)
+	}
+	visit(v)
+	return order
+}
+
+// accumulate adds g into *dst, taking a copy when *dst is still nil
+func accumulate(dst **mat.Dense, g *mat.Dense) {
+	if *dst == nil {
+		*dst = mat.DenseCopyOf(g)
+		return
+	}
+	(*dst).Add(*dst, g)
+}
@@ -11,7 +11,21 @@ type PolynomialRegression = models.PolynomialRegression
 type DecisionTreeClassifier = models.DecisionTreeClassifier
 type MLPClassifier = models.MLPClassifier
 var NewMLPClassifier = models.NewMLPClassifier
-type GaussianNB = models.GaussianNB
+type MLPRegressor = models.MLPRegressor
+var NewMLPRegressor = models.NewMLPRegressor
+type Sequential = models.Sequential
+var NewSequential = models.NewSequential
+var MixedInputs = models.MixedInputs
+type Autoencoder = models.Autoencoder
+var NewAutoencoder = models.NewAutoencoder
+var NewDenoisingAutoencoder = models.NewDenoisingAutoencoder
+var GradientCheck = models.GradientCheck
+type GaussianNB = models.GaussianNBAdapter
+type GaussianNBOf[L comparable] = models.GaussianNB[L]
+type MultinomialNB[L comparable] = models.MultinomialNB[L]
+type ComplementNB[L comparable] = models.ComplementNB[L]
+type BernoulliNB[L comparable] = models.BernoulliNB[L]
+type CategoricalNB[L comparable] = models.CategoricalNB[L]
 
 // utils
 type LabelEncoder = utils.LabelEncoder
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Activation is an element-wise activation function together with its
+// derivative. Derivative receives both the input x and the already
+// computed output y = Activate(x), so functions like sigmoid can reuse it.
+type Activation interface {
+	Activate(x float64) float64
+	Derivative(x, y float64) float64
+}
+
+// ActivationFuncs adapts a pair of plain functions to the Activation
+// interface, which is handy for registering custom activations
+type ActivationFuncs struct {
+	Func  func(x float64) float64
+	Deriv func(x, y float64) float64
+}
+
+func (a ActivationFuncs) Activate(x float64) float64      { return a.Func(x) }
+func (a ActivationFuncs) Derivative(x, y float64) float64 { return a.Deriv(x, y) }
+
+// Sigmoid squashes inputs into (0, 1)
+type Sigmoid struct{}
+
+func (Sigmoid) Activate(x float64) float64      { return sigmoid(x) }
+func (Sigmoid) Derivative(x, y float64) float64 { return sigmoidDerivative(y) }
+
+// Tanh squashes inputs into (-1, 1)
+type Tanh struct{}
+
+func (Tanh) Activate(x float64) float64      { return math.Tanh(x) }
+func (Tanh) Derivative(x, y float64) float64 { return 1 - y*y }
+
+// ReLU is max(0, x)
+type ReLU struct{}
+
+func (ReLU) Activate(x float64) float64 { return math.Max(0, x) }
+func (ReLU) Derivative(x, y float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+// LeakyReLU is x for positive inputs and Alpha*x otherwise
+type LeakyReLU struct {
+	Alpha float64
+}
+
+func (a LeakyReLU) Activate(x float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return a.Alpha * x
+}
+
+func (a LeakyReLU) Derivative(x, y float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return a.Alpha
+}
+
+// ELU is x for positive inputs and Alpha*(exp(x)-1) otherwise
+type ELU struct {
+	Alpha float64
+}
+
+func (a ELU) Activate(x float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return a.Alpha * (math.Exp(x) - 1)
+}
+
+func (a ELU) Derivative(x, y float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return y + a.Alpha
+}
+
+// GELU is the Gaussian error linear unit x*Phi(x), using the exact normal
+// CDF rather than the tanh approximation
+type GELU struct{}
+
+func (GELU) Activate(x float64) float64 {
+	return 0.5 * x * (1 + math.Erf(x/math.Sqrt2))
+}
+
+func (GELU) Derivative(x, y float64) float64 {
+	cdf := 0.5 * (1 + math.Erf(x/math.Sqrt2))
+	pdf := math.Exp(-0.5*x*x) / math.Sqrt(2*math.Pi)
+	return cdf + x*pdf
+}
+
+// Softplus is the smooth approximation log(1 + exp(x)) of ReLU
+type Softplus struct{}
+
+func (Softplus) Activate(x float64) float64 {
+	// log1p(exp(-|x|)) + max(x, 0) avoids overflow for large inputs
+	return math.Log1p(math.Exp(-math.Abs(x))) + math.Max(x, 0)
+}
+
+func (Softplus) Derivative(x, y float64) float64 { return sigmoid(x) }
+
+// Identity leaves its input unchanged
+type Identity struct{}
+
+func (Identity) Activate(x float64) float64      { return x }
+func (Identity) Derivative(x, y float64) float64 { return 1 }
+
+// ----------- Registry -----------
+
+var (
+	activationsMu sync.RWMutex
+	activations   = map[string]Activation{
+		"sigmoid":    Sigmoid{},
+		"tanh":       Tanh{},
+		"relu":       ReLU{},
+		"leaky_relu": LeakyReLU{Alpha: 0.01},
+		"elu":        ELU{Alpha: 1},
+		"gelu":       GELU{},
+		"softplus":   Softplus{},
+		"identity":   Identity{},
+	}
+)
+
+// RegisterActivation makes a custom activation selectable by name,
+// replacing any activation previously registered under that name
+func RegisterActivation(name string, activation Activation) {
+	activationsMu.Lock()
+	defer activationsMu.Unlock()
+	activations[name] = activation
+}
+
+// GetActivation looks up an activation by name
+func GetActivation(name string) (Activation, error) {
+	activationsMu.RLock()
+	defer activationsMu.RUnlock()
+	activation, ok := activations[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown activation %q", name)
+	}
+	return activation, nil
+}
+
+// ActivationNames lists the registered activation names in sorted order
+func ActivationNames() []string {
+	activationsMu.RLock()
+	defer activationsMu.RUnlock()
+	names := make([]string, 0, len(activations))
+	for name := range activations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
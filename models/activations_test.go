@@ -0,0 +1,81 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestActivationDerivatives(t *testing.T) {
+	const h = 1e-6
+	// Points away from the kinks of ReLU, LeakyReLU and ELU at 0
+	points := []float64{-3, -1.2, -0.4, 0.3, 1.1, 2.5}
+	for _, name := range ActivationNames() {
+		activation, err := GetActivation(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range points {
+			numeric := (activation.Activate(x+h) - activation.Activate(x-h)) / (2 * h)
+			analytic := activation.Derivative(x, activation.Activate(x))
+			if math.Abs(analytic-numeric) > 1e-6 {
+				t.Errorf("%s'(%v) is %v, numeric %v", name, x, analytic, numeric)
+			}
+		}
+	}
+}
+
+func TestSoftplusIsStableForLargeInputs(t *testing.T) {
+	if got := (Softplus{}).Activate(1000); got != 1000 {
+		t.Errorf("softplus(1000) is %v, expected 1000", got)
+	}
+	if got := (Softplus{}).Activate(-1000); got != 0 {
+		t.Errorf("softplus(-1000) is %v, expected 0", got)
+	}
+}
+
+func TestRegisterActivation(t *testing.T) {
+	square := ActivationFuncs{
+		Func:  func(x float64) float64 { return x * x },
+		Deriv: func(x, _ float64) float64 { return 2 * x },
+	}
+	RegisterActivation("test_square", square)
+	defer func() {
+		activationsMu.Lock()
+		delete(activations, "test_square")
+		activationsMu.Unlock()
+	}()
+
+	activation, err := GetActivation("test_square")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if activation.Activate(3) != 9 || activation.Derivative(3, 9) != 6 {
+		t.Error("the registered activation is not the one returned")
+	}
+
+	mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, nil)
+	if err := mlp.SetActivation(0, "test_square"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mlp.Layers[0].(*DenseLayer).Activation.Activate(3); got != 9 {
+		t.Errorf("SetActivation did not install the activation: f(3) = %v", got)
+	}
+}
+
+func TestActivationErrors(t *testing.T) {
+	if _, err := GetActivation("no_such_activation"); err == nil {
+		t.Error("GetActivation: expected an error for an unknown name")
+	}
+	mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, nil)
+	if err := mlp.SetActivation(0, "no_such_activation"); err == nil {
+		t.Error("SetActivation: expected an error for an unknown name")
+	}
+	if _, ok := mlp.Layers[0].(*DenseLayer).Activation.(Sigmoid); !ok {
+		t.Error("a failed SetActivation changed the activation")
+	}
+	for _, layer := range []int{-1, 2} {
+		if err := mlp.SetActivation(layer, "relu"); err == nil {
+			t.Errorf("SetActivation: expected an error for layer %d", layer)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Autoencoder learns to reconstruct its input through a narrow code layer.
+// The encoder goes from the input through the hidden layers to the code
+// and the decoder mirrors it back, so Encode reduces dimensionality and
+// ReconstructionError flags samples unlike the training data.
+type Autoencoder struct {
+	Network
+	Loss Loss // nil uses MeanSquaredError
+
+	// CodeLayer is the index in Layers of the layer whose output is the
+	// code: Encode runs the layers up to it and Decode the ones after it
+	CodeLayer int
+}
+
+// NewAutoencoder creates a symmetric autoencoder with sigmoid hidden
+// layers of the given sizes, from input to code, and linear code and
+// output layers. src seeds the network like in NewMLPClassifier
+func NewAutoencoder(inputNodes int, hiddenLayerSizes []int, codeSize int, learningRate float64, src rand.Source) *Autoencoder {
+	ae := &Autoencoder{Network: Network{
+		InputNodes:       inputNodes,
+		HiddenLayerSizes: append([]int(nil), hiddenLayerSizes...),
+		OutputNodes:      inputNodes,
+		LearningRate:     learningRate,
+		BatchSize:        defaultBatchSize,
+		Shuffle:          true,
+	}}
+
+	// Encoder
+	inputs := inputNodes
+	for _, size := range hiddenLayerSizes {
+		ae.Layers = append(ae.Layers, NewDenseLayer(inputs, size, Sigmoid{}))
+		inputs = size
+	}
+	ae.Layers = append(ae.Layers, NewDenseLayer(inputs, codeSize, Identity{}))
+	ae.CodeLayer = len(ae.Layers) - 1
+
+	// Decoder
+	inputs = codeSize
+	for i := len(hiddenLayerSizes) - 1; i >= 0; i-- {
+		ae.Layers = append(ae.Layers, NewDenseLayer(inputs, hiddenLayerSizes[i], Sigmoid{}))
+		inputs = hiddenLayerSizes[i]
+	}
+	ae.Layers = append(ae.Layers, NewDenseLayer(inputs, inputNodes, Identity{}))
+	if src != nil {
+		ae.Initialize(src)
+	}
+	return ae
+}
+
+// NewDenoisingAutoencoder creates an autoencoder that corrupts its inputs
+// with Gaussian noise of the given standard deviation while training, and
+// still has to reconstruct the clean samples
+func NewDenoisingAutoencoder(inputNodes int, hiddenLayerSizes []int, codeSize int, learningRate, noiseStdDev float64, src rand.Source) *Autoencoder {
+	ae := NewAutoencoder(inputNodes, hiddenLayerSizes, codeSize, learningRate, nil)
+	ae.Layers = append([]Layer{NewGaussianNoiseLayer(noiseStdDev)}, ae.Layers...)
+	ae.CodeLayer++
+	if src != nil {
+		ae.Initialize(src)
+	}
+	return ae
+}
+
+// loss returns the configured loss or MeanSquaredError
+func (ae *Autoencoder) loss() Loss {
+	if ae.Loss == nil {
+		return MeanSquaredError{}
+	}
+	return ae.Loss
+}
+
+// Fit trains the autoencoder to reconstruct X for the given number of
+// epochs and returns the per-epoch History
+func (ae *Autoencoder) Fit(X [][]float64, epochs int) (*History, error) {
+	return ae.FitContext(context.Background(), X, epochs)
+}
+
+// FitContext is Fit with cancellation, see MLPClassifier.FitContext
+func (ae *Autoencoder) FitContext(ctx context.Context, X [][]float64, epochs int) (*History, error) {
+	return ae.fit(ctx, X, X, epochs, ae.loss(), nil)
+}
+
+// runLayers passes the samples through the given layers in inference mode
+func runLayers(layers []Layer, inputs *mat.Dense) *mat.Dense {
+	for _, layer := range layers {
+		inputs = layer.Forward(inputs, false)
+	}
+	return inputs
+}
+
+// checkCodeLayer verifies that CodeLayer is the index of a layer
+func (ae *Autoencoder) checkCodeLayer() error {
+	if ae.CodeLayer < 0 || ae.CodeLayer >= len(ae.Layers) {
+		return fmt.Errorf("CodeLayer is %d, expected an index in [0, %d)", ae.CodeLayer, len(ae.Layers))
+	}
+	return nil
+}
+
+// checkCodes verifies that every code has the width of the code layer
+// output, found by encoding a blank sample
+func (ae *Autoencoder) checkCodes(codes [][]float64) error {
+	if err := ae.checkCodeLayer(); err != nil {
+		return err
+	}
+	if err := ae.checkLayers(); err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return errors.New("codes is empty")
+	}
+	_, codeSize := runLayers(ae.Layers[:ae.CodeLayer+1], mat.NewDense(1, ae.InputNodes, nil)).Dims()
+	for i, code := range codes {
+		if len(code) != codeSize {
+			return fmt.Errorf("code %d has %d values, expected %d", i, len(code), codeSize)
+		}
+	}
+	return nil
+}
+
+// Encode returns the code of every sample. Rows of the wrong width are an
+// error, as in MLPClassifier.PredictProba
+func (ae *Autoencoder) Encode(X [][]float64) ([][]float64, error) {
+	if err := ae.checkCodeLayer(); err != nil {
+		return nil, err
+	}
+	if err := ae.checkInputs(X); err != nil {
+		return nil, err
+	}
+	return denseToRows(runLayers(ae.Layers[:ae.CodeLayer+1], rowsToDense(X))), nil
+}
+
+// Decode maps codes back to the input space. Every code must have the
+// width of the code layer output
+func (ae *Autoencoder) Decode(codes [][]float64) ([][]float64, error) {
+	if err := ae.checkCodes(codes); err != nil {
+		return nil, err
+	}
+	return denseToRows(runLayers(ae.Layers[ae.CodeLayer+1:], rowsToDense(codes))), nil
+}
+
+// Reconstruct encodes and decodes every sample
+func (ae *Autoencoder) Reconstruct(X [][]float64) ([][]float64, error) {
+	if err := ae.checkInputs(X); err != nil {
+		return nil, err
+	}
+	return denseToRows(runLayers(ae.Layers, rowsToDense(X))), nil
+}
+
+// ReconstructionError returns the loss between every sample and its
+// reconstruction, which is larger for anomalies
+func (ae *Autoencoder) ReconstructionError(X [][]float64) ([]float64, error) {
+	reconstructed, err := ae.Reconstruct(X)
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(X))
+	loss := ae.loss()
+	for i := range X {
+		scores[i] = loss.Loss(mat.NewDense(1, len(X[i]), reconstructed[i]), mat.NewDense(1, len(X[i]), X[i]))
+	}
+	return scores, nil
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// lowRankData returns n samples of six features that are linear mixes of
+// two latent factors, so a two-unit code can reconstruct them
+func lowRankData(n int, seed int64) [][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	X := make([][]float64, n)
+	for i := range X {
+		a, b := rng.NormFloat64(), rng.NormFloat64()
+		X[i] = []float64{a, b, a + b, a - b, 0.5 * a, -b}
+	}
+	return X
+}
+
+func meanOf(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// reconstructionError returns the mean reconstruction error of X
+func reconstructionError(t *testing.T, ae *Autoencoder, X [][]float64) float64 {
+	t.Helper()
+	scores, err := ae.ReconstructionError(X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return meanOf(scores)
+}
+
+func TestAutoencoderLearnsLowRankData(t *testing.T) {
+	X := lowRankData(128, 1)
+	ae := NewAutoencoder(6, []int{8}, 2, 0.01, rand.NewSource(2))
+	ae.Optimizer = NewAdam(0.01)
+	before := reconstructionError(t, ae, X)
+	if _, err := ae.Fit(X, 100); err != nil {
+		t.Fatal(err)
+	}
+	after := reconstructionError(t, ae, X)
+	if after > before/10 {
+		t.Errorf("mean reconstruction error went from %v to %v, expected a tenfold drop", before, after)
+	}
+
+	codes, err := ae.Encode(X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != len(X) || len(codes[0]) != 2 {
+		t.Fatalf("Encode returned %dx%d codes, expected %dx2", len(codes), len(codes[0]), len(X))
+	}
+	decoded, err := ae.Decode(codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reconstructed, err := ae.Reconstruct(X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, reconstructed) {
+		t.Error("Decode(Encode(X)) differs from Reconstruct(X)")
+	}
+}
+
+func TestDenoisingAutoencoderIsDeterministicAtInference(t *testing.T) {
+	X := lowRankData(64, 3)
+	ae := NewDenoisingAutoencoder(6, []int{8}, 2, 0.05, 0.3, rand.NewSource(4))
+	if _, err := ae.Fit(X, 5); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := ae.Reconstruct(X)
+	second, _ := ae.Reconstruct(X)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Reconstruct differs between calls, the noise layer is active at inference")
+	}
+	if reconstructionError(t, ae, X) != reconstructionError(t, ae, X) {
+		t.Error("ReconstructionError differs between calls")
+	}
+}
+
+func TestAutoencoderRejectsBadWidths(t *testing.T) {
+	ae := NewAutoencoder(6, []int{4}, 2, 0.01, nil)
+	good := lowRankData(2, 1)
+	ragged := [][]float64{good[0], good[1][:5]}
+	if _, err := ae.Encode(ragged); err == nil {
+		t.Error("Encode: expected an error for a ragged row")
+	}
+	if _, err := ae.Reconstruct([][]float64{{1, 2}}); err == nil {
+		t.Error("Reconstruct: expected an error for 2 features")
+	}
+	if _, err := ae.ReconstructionError(ragged); err == nil {
+		t.Error("ReconstructionError: expected an error for a ragged row")
+	}
+	if _, err := ae.Encode(nil); err == nil {
+		t.Error("Encode: expected an error for no samples")
+	}
+	if _, err := ae.Decode([][]float64{{1, 2}, {1, 2, 3}}); err == nil {
+		t.Error("Decode: expected an error for a code of 3 values")
+	}
+	if _, err := ae.Decode([][]float64{{1, 2}}); err != nil {
+		t.Errorf("Decode: %v", err)
+	}
+	ae.CodeLayer = len(ae.Layers)
+	if _, err := ae.Encode(good); err == nil {
+		t.Error("Encode: expected an error for CodeLayer out of range")
+	}
+}
@@ -0,0 +1,95 @@
+package models
+
+import "errors"
+
+// ErrStopTraining can be returned by a Callback to end training early.
+// Fit then returns the History so far without an error.
+var ErrStopTraining = errors.New("training stopped by callback")
+
+// EpochLogs summarizes a finished epoch. The validation metrics are NaN
+// when no validation split is used.
+type EpochLogs struct {
+	Loss               float64
+	Accuracy           float64
+	ValidationLoss     float64
+	ValidationAccuracy float64
+	LearningRate       float64
+}
+
+// Callback observes iterative training, e.g. to draw progress bars, log
+// metrics, save checkpoints or apply custom stopping rules. Returning an
+// error from any method stops training; see ErrStopTraining.
+type Callback interface {
+	OnEpochBegin(epoch int) error
+	OnEpochEnd(epoch int, logs EpochLogs) error
+	OnBatchEnd(batch int, loss float64) error
+}
+
+// CallbackFuncs implements Callback with optional functions; nil fields
+// are skipped
+type CallbackFuncs struct {
+	EpochBegin func(epoch int) error
+	EpochEnd   func(epoch int, logs EpochLogs) error
+	BatchEnd   func(batch int, loss float64) error
+}
+
+// OnEpochBegin calls EpochBegin if it is set
+func (c CallbackFuncs) OnEpochBegin(epoch int) error {
+	if c.EpochBegin == nil {
+		return nil
+	}
+	return c.EpochBegin(epoch)
+}
+
+// OnEpochEnd calls EpochEnd if it is set
+func (c CallbackFuncs) OnEpochEnd(epoch int, logs EpochLogs) error {
+	if c.EpochEnd == nil {
+		return nil
+	}
+	return c.EpochEnd(epoch, logs)
+}
+
+// OnBatchEnd calls BatchEnd if it is set
+func (c CallbackFuncs) OnBatchEnd(batch int, loss float64) error {
+	if c.BatchEnd == nil {
+		return nil
+	}
+	return c.BatchEnd(batch, loss)
+}
+
+func notifyEpochBegin(callbacks []Callback, epoch int) error {
+	for _, c := range callbacks {
+		if err := c.OnEpochBegin(epoch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notifyEpochEnd(callbacks []Callback, epoch int, logs EpochLogs) error {
+	for _, c := range callbacks {
+		if err := c.OnEpochEnd(epoch, logs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notifyBatchEnd(callbacks []Callback, batch int, loss float64) error {
+	for _, c := range callbacks {
+		if err := c.OnBatchEnd(batch, loss); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopError turns ErrStopTraining into a clean stop recorded in the
+// History and passes any other error through
+func stopError(history *History, err error) error {
+	if errors.Is(err, ErrStopTraining) {
+		history.Stopped = true
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// xorClassifier returns a classifier on xorData taking two mini-batches
+// per epoch, so that batch callbacks run more than once
+func xorClassifier() *MLPClassifier {
+	mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, nil)
+	mlp.BatchSize = 2
+	mlp.Shuffle = false
+	return mlp
+}
+
+func TestCallbackOrder(t *testing.T) {
+	X, Y := xorData()
+	mlp := xorClassifier()
+	var events []string
+	record := func(name string) Callback {
+		return CallbackFuncs{
+			EpochBegin: func(epoch int) error {
+				events = append(events, fmt.Sprintf("%s begin %d", name, epoch))
+				return nil
+			},
+			EpochEnd: func(epoch int, _ EpochLogs) error {
+				events = append(events, fmt.Sprintf("%s end %d", name, epoch))
+				return nil
+			},
+			BatchEnd: func(batch int, _ float64) error {
+				events = append(events, fmt.Sprintf("%s batch %d", name, batch))
+				return nil
+			},
+		}
+	}
+	mlp.Callbacks = []Callback{record("a"), record("b"), CallbackFuncs{}}
+	if _, err := mlp.Fit(X, Y, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for epoch := 0; epoch < 2; epoch++ {
+		want = append(want, fmt.Sprintf("a begin %d", epoch), fmt.Sprintf("b begin %d", epoch),
+			"a batch 0", "b batch 0", "a batch 1", "b batch 1",
+			fmt.Sprintf("a end %d", epoch), fmt.Sprintf("b end %d", epoch))
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got events\n%v\nexpected\n%v", events, want)
+	}
+}
+
+func TestFitContextCancelled(t *testing.T) {
+	X, Y := xorData()
+	mlp := xorClassifier()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mlp.Callbacks = []Callback{CallbackFuncs{EpochEnd: func(epoch int, _ EpochLogs) error {
+		if epoch == 1 {
+			cancel()
+		}
+		return nil
+	}}}
+
+	history, err := mlp.FitContext(ctx, X, Y, 5)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, expected context.Canceled", err)
+	}
+	if history == nil || len(history.Loss) != 2 {
+		t.Fatalf("got history %+v, expected the 2 finished epochs", history)
+	}
+	if history.Stopped {
+		t.Error("a cancelled run is not a clean stop")
+	}
+}
+
+func TestCallbackStopsTraining(t *testing.T) {
+	X, Y := xorData()
+	mlp := xorClassifier()
+	mlp.Callbacks = []Callback{CallbackFuncs{EpochEnd: func(epoch int, _ EpochLogs) error {
+		if epoch == 2 {
+			return ErrStopTraining
+		}
+		return nil
+	}}}
+	history, err := mlp.Fit(X, Y, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !history.Stopped || len(history.Loss) != 3 {
+		t.Errorf("got Stopped %v after %d epochs, expected a stop after 3", history.Stopped, len(history.Loss))
+	}
+
+	// Any other error aborts training and is returned
+	failure := errors.New("disk full")
+	mlp.Callbacks = []Callback{CallbackFuncs{BatchEnd: func(int, float64) error { return failure }}}
+	history, err = mlp.Fit(X, Y, 10)
+	if !errors.Is(err, failure) {
+		t.Errorf("got error %v, expected %v", err, failure)
+	}
+	if history == nil || len(history.Loss) != 0 {
+		t.Errorf("got history %+v, expected no finished epoch", history)
+	}
+}
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/snugml/go/autograd"
+	"gonum.org/v1/gonum/mat"
+)
+
+// The spatial layers work on images stored one per row in channels-last
+// order: the value of pixel (y, x) in channel c is at column
+// (y*width + x)*channels + c. A grayscale 28x28 image is simply its 784
+// pixels row by row.
+
+// Conv2D convolves square kernels over the input images, computed as a
+// product of the im2col patches with the kernel matrix. The output holds
+// one channel per filter.
+type Conv2D struct {
+	Kernel *Parameter // kernelSize*kernelSize*channels x filters, rows ordered (ky, kx, channel)
+	Bias   *Parameter // 1 x filters
+
+	Activation Activation
+
+	// WeightInit and BiasInit fill the parameters when the layer is
+	// created or reinitialized
+	WeightInit Initializer
+	BiasInit   Initializer
+
+	window autograd.Window
+	tape   *tape
+}
+
+// NewConv2D creates a convolution over height x width images with the
+// given number of channels. A nil activation defaults to ReLU; kernels
+// start with He uniform values and biases at zero
+func NewConv2D(height, width, channels, filters, kernelSize, stride, padding int, activation Activation) *Conv2D {
+	if activation == nil {
+		activation = ReLU{}
+	}
+	if stride <= 0 {
+		stride = 1
+	}
+	patch := kernelSize * kernelSize * channels
+	l := &Conv2D{
+		Kernel:     newParameter(mat.NewDense(patch, filters, nil)),
+		Bias:       newParameter(mat.NewDense(1, filters, nil)),
+		Activation: activation,
+		WeightInit: HeUniform{},
+		BiasInit:   Zeros{},
+		window: autograd.Window{
+			Height: height, Width: width, Channels: channels,
+			KernelHeight: kernelSize, KernelWidth: kernelSize,
+			Stride: stride, Padding: padding,
+		},
+	}
+	l.Kernel.Regularize = true
+	l.Initialize(nil)
+	return l
+}
+
+// Initialize redraws the kernel and biases from WeightInit and BiasInit
+// using rng, or the global math/rand source when rng is nil
+func (l *Conv2D) Initialize(rng *rand.Rand) {
+	patch, filters := l.Kernel.Value.Dims()
+	l.Kernel.Value.Copy(l.WeightInit.Initialize(patch, filters, rng))
+	l.Bias.Value.Copy(l.BiasInit.Initialize(1, filters, rng))
+}
+
+// OutputShape returns the height, width and channels of the output images
+func (l *Conv2D) OutputShape() (int, int, int) {
+	height, width := l.window.OutputSize()
+	_, filters := l.Kernel.Value.Dims()
+	return height, width, filters
+}
+
+func (l *Conv2D) Forward(input *mat.Dense, training bool) *mat.Dense {
+	samples, _ := input.Dims()
+	height, width, filters := l.OutputShape()
+	positions := height * width
+
+	t := newTape(input, training, l.Kernel, l.Bias)
+	patches := autograd.Im2Col(t.input, l.window)
+	convolved := autograd.Add(autograd.MatMul(patches, t.vars[0]), autograd.BroadcastRows(t.vars[1], samples*positions))
+	// Rows of positions x filters become channels-last images
+	images := autograd.Reshape(convolved, samples, positions*filters)
+	t.output = applyActivation(images, l.Activation)
+	l.tape = t
+	return t.output.Value
+}
+
+func (l *Conv2D) Backward(outputGradient *mat.Dense) *mat.Dense {
+	return l.tape.backward(outputGradient)
+}
+
+// Parameters returns the kernel and the bias
+func (l *Conv2D) Parameters() []*Parameter {
+	return []*Parameter{l.Kernel, l.Bias}
+}
+
+func (l *Conv2D) outputWidth(inputs int) (int, error) {
+	height, width, filters := l.OutputShape()
+	return height * width * filters, checkWindow(l.window, inputs)
+}
+
+// MaxPool2D keeps the largest value of every channel in each pooling
+// window
+type MaxPool2D struct {
+	window autograd.Window
+	tape   *tape
+}
+
+// NewMaxPool2D creates a max pooling layer over height x width images.
+// A stride of 0 uses non-overlapping windows
+func NewMaxPool2D(height, width, channels, poolSize, stride int) *MaxPool2D {
+	return &MaxPool2D{window: poolWindow(height, width, channels, poolSize, stride)}
+}
+
+// OutputShape returns the height, width and channels of the output images
+func (l *MaxPool2D) OutputShape() (int, int, int) {
+	height, width := l.window.OutputSize()
+	return height, width, l.window.Channels
+}
+
+func (l *MaxPool2D) Forward(input *mat.Dense, training bool) *mat.Dense {
+	t := newTape(input, training)
+	t.output = autograd.MaxPool(t.input, l.window)
+	l.tape = t
+	return t.output.Value
+}
+
+func (l *MaxPool2D) Backward(outputGradient *mat.Dense) *mat.Dense {
+	return l.tape.backward(outputGradient)
+}
+
+func (l *MaxPool2D) Parameters() []*Parameter { return nil }
+
+func (l *MaxPool2D) outputWidth(inputs int) (int, error) {
+	height, width, channels := l.OutputShape()
+	return height * width * channels, checkWindow(l.window, inputs)
+}
+
+// AvgPool2D averages every channel over each pooling window
+type AvgPool2D struct {
+	window autograd.Window
+	tape   *tape
+}
+
+// NewAvgPool2D creates an average pooling layer over height x width
+// images. A stride of 0 uses non-overlapping windows
+func NewAvgPool2D(height, width, channels, poolSize, stride int) *AvgPool2D {
+	return &AvgPool2D{window: poolWindow(height, width, channels, poolSize, stride)}
+}
+
+// OutputShape returns the height, width and channels of the output images
+func (l *AvgPool2D) OutputShape() (int, int, int) {
+	height, width := l.window.OutputSize()
+	return height, width, l.window.Channels
+}
+
+func (l *AvgPool2D) Forward(input *mat.Dense, training bool) *mat.Dense {
+	t := newTape(input, training)
+	t.output = autograd.AvgPool(t.input, l.window)
+	l.tape = t
+	return t.output.Value
+}
+
+func (l *AvgPool2D) Backward(outputGradient *mat.Dense) *mat.Dense {
+	return l.tape.backward(outputGradient)
+}
+
+func (l *AvgPool2D) Parameters() []*Parameter { return nil }
+
+func (l *AvgPool2D) outputWidth(inputs int) (int, error) {
+	height, width, channels := l.OutputShape()
+	return height * width * channels, checkWindow(l.window, inputs)
+}
+
+// poolWindow builds the window of a pooling layer
+func poolWindow(height, width, channels, poolSize, stride int) autograd.Window {
+	if stride <= 0 {
+		stride = poolSize
+	}
+	return autograd.Window{
+		Height: height, Width: width, Channels: channels,
+		KernelHeight: poolSize, KernelWidth: poolSize,
+		Stride: stride,
+	}
+}
+
+// checkWindow verifies that a window fits inside its padded input images,
+// which must come in rows of the given width. A kernel larger than the
+// padded image would leave no output positions
+func checkWindow(w autograd.Window, inputs int) error {
+	if w.Height <= 0 || w.Width <= 0 || w.Channels <= 0 {
+		return fmt.Errorf("input images of %dx%dx%d", w.Height, w.Width, w.Channels)
+	}
+	if inputs != w.Height*w.Width*w.Channels {
+		return fmt.Errorf("%d inputs, expected %dx%dx%d images", inputs, w.Height, w.Width, w.Channels)
+	}
+	if w.KernelHeight <= 0 || w.KernelWidth <= 0 || w.Padding < 0 {
+		return fmt.Errorf("%dx%d window with padding %d", w.KernelHeight, w.KernelWidth, w.Padding)
+	}
+	if w.KernelHeight > w.Height+2*w.Padding || w.KernelWidth > w.Width+2*w.Padding {
+		return fmt.Errorf("%dx%d window larger than the %dx%d images with padding %d",
+			w.KernelHeight, w.KernelWidth, w.Height, w.Width, w.Padding)
+	}
+	return nil
+}
+
+// Flatten marks the end of the spatial layers. Images are already stored
+// as flat rows, so it passes them through unchanged to the dense layers
+type Flatten struct{}
+
+// NewFlatten creates a Flatten layer
+func NewFlatten() *Flatten {
+	return &Flatten{}
+}
+
+func (l *Flatten) Forward(input *mat.Dense, training bool) *mat.Dense { return input }
+
+func (l *Flatten) Backward(outputGradient *mat.Dense) *mat.Dense { return outputGradient }
+
+func (l *Flatten) Parameters() []*Parameter { return nil }
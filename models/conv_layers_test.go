@@ -0,0 +1,59 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// gradientCheckRows returns n rows of cols standard normal values
+func gradientCheckRows(rng *rand.Rand, n, cols int) [][]float64 {
+	X := make([][]float64, n)
+	for i := range X {
+		X[i] = make([]float64, cols)
+		for j := range X[i] {
+			X[i][j] = rng.NormFloat64()
+		}
+	}
+	return X
+}
+
+func TestConvAndPoolOutputShapes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	cases := []struct {
+		name    string
+		layer   interface{ OutputShape() (int, int, int) }
+		inputs  int
+		h, w, c int
+	}{
+		{"Conv2D valid", NewConv2D(6, 5, 2, 4, 3, 1, 0, nil), 6 * 5 * 2, 4, 3, 4},
+		{"Conv2D same", NewConv2D(6, 5, 2, 4, 3, 1, 1, nil), 6 * 5 * 2, 6, 5, 4},
+		{"Conv2D strided", NewConv2D(7, 7, 1, 3, 3, 2, 1, nil), 7 * 7, 4, 4, 3},
+		{"MaxPool2D", NewMaxPool2D(6, 4, 3, 2, 0), 6 * 4 * 3, 3, 2, 3},
+		{"MaxPool2D overlapping", NewMaxPool2D(5, 5, 2, 3, 1), 5 * 5 * 2, 3, 3, 2},
+		{"AvgPool2D", NewAvgPool2D(6, 4, 3, 2, 2), 6 * 4 * 3, 3, 2, 3},
+	}
+	for _, c := range cases {
+		h, w, ch := c.layer.OutputShape()
+		if h != c.h || w != c.w || ch != c.c {
+			t.Errorf("%s: OutputShape %dx%dx%d, expected %dx%dx%d", c.name, h, w, ch, c.h, c.w, c.c)
+		}
+		output := c.layer.(Layer).Forward(rowsToDense(gradientCheckRows(rng, 3, c.inputs)), false)
+		if rows, cols := output.Dims(); rows != 3 || cols != h*w*ch {
+			t.Errorf("%s: output is %dx%d, expected 3x%d", c.name, rows, cols, h*w*ch)
+		}
+	}
+}
+
+func TestFlattenPassesThrough(t *testing.T) {
+	l := NewFlatten()
+	input := rowsToDense([][]float64{{1, 2, 3}, {4, 5, 6}})
+	if l.Forward(input, true) != input {
+		t.Error("Forward changed its input")
+	}
+	if l.Backward(input) != input {
+		t.Error("Backward changed its gradient")
+	}
+	if len(l.Parameters()) != 0 {
+		t.Error("Flatten has parameters")
+	}
+}
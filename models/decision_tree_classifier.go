@@ -4,15 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
+	"sort"
 	"strings"
 )
 
-// Nodo del árbol
+// Nodo del árbol. Fit calcula Majority al construir cada nodo interno; en
+// un árbol armado a mano hay que asignarla, porque si se deja en 0 los
+// valores no vistos en ese nodo se predicen como la etiqueta 0
 type Node struct {
 	Label         int        // Nodo hoja: etiqueta codificada como int; -1 si no hoja
 	FeatureIndex  int        // Índice del atributo para dividir; -1 si hoja
 	FeatureValues []int      // Valores únicos del atributo para ramificar
 	Children      []ChildNode
+	Majority      int        // Nodo interno: etiqueta mayoritaria del sub-árbol para valores no vistos
 }
 
 type ChildNode struct {
@@ -21,9 +26,17 @@ type ChildNode struct {
 }
 
 type DecisionTreeClassifier struct {
-	Tree     *Node
-	MaxDepth int
-	Gain     string // string para almacenar texto con info
+	Tree        *Node
+	MaxDepth    int
+	Gain        string // string para almacenar texto con info
+	RandomState int64  // Semilla para desempatar atributos y etiquetas de forma reproducible
+
+	rng *rand.Rand
+}
+
+// newRand crea un generador aleatorio a partir de RandomState
+func (dt *DecisionTreeClassifier) newRand() *rand.Rand {
+	return rand.New(rand.NewSource(dt.RandomState))
 }
 
 // Fit entrena el árbol con datos X (atributos) e y (etiquetas)
@@ -35,6 +48,8 @@ func (dt *DecisionTreeClassifier) Fit(X [][]int, y []int) error {
 		return errors.New("X and y have different lengths")
 	}
 	// Inicializar Label y FeatureIndex en nodos hoja con -1
+	dt.rng = dt.newRand()
+	dt.Gain = ""
 	dt.Tree = dt.buildTree(X, y, 0)
 	return nil
 }
@@ -50,13 +65,13 @@ func (dt *DecisionTreeClassifier) buildTree(X [][]int, y []int, depth int) *Node
 
 	// Caso base 2: Profundidad máxima o no hay atributos
 	if depth >= dt.MaxDepth || (len(X) > 0 && len(X[0]) == 0) {
-		majority := majorityLabel(y)
+		majority := majorityLabel(y, dt.rng)
 		return &Node{Label: majority, FeatureIndex: -1}
 	}
 
 	bestFeature := dt.bestSplit(X, y)
 	if bestFeature == -1 {
-		majority := majorityLabel(y)
+		majority := majorityLabel(y, dt.rng)
 		return &Node{Label: majority, FeatureIndex: -1}
 	}
 
@@ -84,6 +99,10 @@ func (dt *DecisionTreeClassifier) buildTree(X [][]int, y []int, depth int) *Node
 		node.Children = append(node.Children, ChildNode{Value: val, ChildNode: child})
 	}
 
+	// El desempate se hace aquí, una sola vez, para que Predict no dependa
+	// de la posición de la fila en el lote
+	node.Majority = majorityLabel(dt.getAllLabels(node), dt.rng)
+
 	return node
 }
 
@@ -125,20 +144,24 @@ func (dt *DecisionTreeClassifier) informationGain(X [][]int, y []int, featureInd
 // Busca el mejor atributo para dividir
 func (dt *DecisionTreeClassifier) bestSplit(X [][]int, y []int) int {
 	bestGain := math.Inf(-1)
-	bestFeature := -1
 
 	if len(X) == 0 || len(X[0]) == 0 {
 		return -1
 	}
 
+	// Los empates se resuelven con el generador sembrado con RandomState
+	ties := []int{}
 	for i := 0; i < len(X[0]); i++ {
 		gain := dt.informationGain(X, y, i)
 		dt.Gain += "Gain of column " + itoa(i) + ": " + ftoa(gain) + "\n"
 		if gain > bestGain {
 			bestGain = gain
-			bestFeature = i
+			ties = []int{i}
+		} else if gain == bestGain {
+			ties = append(ties, i)
 		}
 	}
+	bestFeature := pickInt(ties, dt.rng)
 	dt.Gain += "** Best feature: " + itoa(bestFeature) + "\n"
 
 	if bestGain <= 0 {
@@ -178,7 +201,7 @@ func (dt *DecisionTreeClassifier) predictRow(row []int, node *Node) int {
 
 	if child == nil {
 		// Si no se encuentra el valor, retorna la etiqueta mayoritaria del sub-árbol
-		return majorityLabel(dt.getAllLabels(node))
+		return node.Majority
 	}
 
 	filteredRow := removeIndexInt(row, node.FeatureIndex)
@@ -222,6 +245,7 @@ func (dt *DecisionTreeClassifier) printTree(node *Node, depth int) string {
 
 // Funciones auxiliares
 
+// uniqueInts devuelve los valores únicos ordenados de menor a mayor
 func uniqueInts(arr []int) []int {
 	m := map[int]struct{}{}
 	for _, v := range arr {
@@ -231,23 +255,39 @@ func uniqueInts(arr []int) []int {
 	for k := range m {
 		res = append(res, k)
 	}
+	sort.Ints(res)
 	return res
 }
 
-func majorityLabel(y []int) int {
+// majorityLabel devuelve la etiqueta más frecuente; los empates se
+// resuelven con rng (o la menor etiqueta si rng es nil)
+func majorityLabel(y []int, rng *rand.Rand) int {
 	counts := map[int]int{}
 	for _, label := range y {
 		counts[label]++
 	}
-	var maxCount int
-	var majority int = -1
-	for label, count := range counts {
-		if count > maxCount {
-			maxCount = count
-			majority = label
+	maxCount := 0
+	ties := []int{}
+	for _, label := range uniqueInts(y) {
+		if counts[label] > maxCount {
+			maxCount = counts[label]
+			ties = []int{label}
+		} else if counts[label] == maxCount {
+			ties = append(ties, label)
 		}
 	}
-	return majority
+	return pickInt(ties, rng)
+}
+
+// pickInt elige un elemento de una lista ordenada; -1 si está vacía
+func pickInt(candidates []int, rng *rand.Rand) int {
+	if len(candidates) == 0 {
+		return -1
+	}
+	if len(candidates) == 1 || rng == nil {
+		return candidates[0]
+	}
+	return candidates[rng.Intn(len(candidates))]
 }
 
 func getColumn(X [][]int, col int) []int {
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecisionTreeSameSeedIsReproducible(t *testing.T) {
+	// Every column ties on gain and every leaf label is unique, so the
+	// seeded generator decides both the split and the fallback label
+	X := [][]int{{0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	y := []int{0, 1, 2, 3}
+	queries := [][]int{{0, 9}, {9, 0}, {9, 9}, {1, 1}}
+
+	var trees []string
+	var nodes []*Node
+	var predictions [][]int
+	for run := 0; run < 2; run++ {
+		dt := &DecisionTreeClassifier{MaxDepth: 3, RandomState: 7}
+		if err := dt.Fit(X, y); err != nil {
+			t.Fatal(err)
+		}
+		preds, err := dt.Predict(queries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trees = append(trees, dt.PrintTree())
+		nodes = append(nodes, dt.Tree)
+		predictions = append(predictions, preds)
+	}
+	if trees[0] != trees[1] {
+		t.Errorf("PrintTree differs between runs:\n%s\n%s", trees[0], trees[1])
+	}
+	if !reflect.DeepEqual(nodes[0], nodes[1]) {
+		t.Error("the trees differ between runs, including the majority labels")
+	}
+	if !reflect.DeepEqual(predictions[0], predictions[1]) {
+		t.Errorf("Predict differs between runs: %v and %v", predictions[0], predictions[1])
+	}
+
+	// Otras semillas desempatan de otra forma
+	varied := false
+	for seed := int64(0); seed < 10 && !varied; seed++ {
+		dt := &DecisionTreeClassifier{MaxDepth: 3, RandomState: seed}
+		if err := dt.Fit(X, y); err != nil {
+			t.Fatal(err)
+		}
+		varied = !reflect.DeepEqual(dt.Tree, nodes[0])
+	}
+	if !varied {
+		t.Error("ten seeds built the same tree, RandomState does not break the ties")
+	}
+}
+
+func TestDecisionTreePredictDoesNotDependOnBatch(t *testing.T) {
+	dt := &DecisionTreeClassifier{MaxDepth: 3, RandomState: 1}
+	if err := dt.Fit([][]int{{0}, {1}, {2}, {3}}, []int{0, 1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	single, err := dt.Predict([][]int{{9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := dt.Predict([][]int{{9}, {9}, {9}, {9}, {9}, {9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, label := range batch {
+		if label != single[0] {
+			t.Errorf("row %d: got %d, expected %d as for a single row", i, label, single[0])
+		}
+	}
+}
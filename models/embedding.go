@@ -0,0 +1,258 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Embedding replaces an integer ID column of its input, such as the
+// indices produced by utils.LabelEncoder, with a learned vector of
+// Dimensions values. The other columns pass through, so the output has
+// the columns before the ID, its embedding and the columns after it.
+//
+// Only the rows of the table used by a batch receive gradients: they are
+// listed in the SparseRows of Table, so optimizers leave the rest of a
+// large vocabulary untouched. For the same reason the lookup is
+// differentiated by hand rather than through autograd, which would build
+// a dense gradient of the whole table on every batch.
+//
+// Networks check the IDs before running their layers and report bad ones
+// as errors. When the layer is used on its own, an ID outside the
+// vocabulary embeds as a zero vector and receives no gradient.
+type Embedding struct {
+	Table  *Parameter // vocabSize x dimensions
+	Column int        // Input column holding the IDs
+	Inputs int        // Number of input columns
+
+	// TableInit fills the table when the layer is created or reinitialized
+	TableInit Initializer
+
+	ids []int // IDs of the last forward pass; -1 for invalid ones
+}
+
+// NewEmbedding creates an embedding of the IDs in [0, vocabSize) found in
+// the given column of inputs-wide rows. The table starts with values drawn
+// from N(0, 0.05^2)
+func NewEmbedding(inputs, column, vocabSize, dimensions int) *Embedding {
+	l := &Embedding{
+		Table:     newParameter(mat.NewDense(vocabSize, dimensions, nil)),
+		Column:    column,
+		Inputs:    inputs,
+		TableInit: embeddingInit{},
+	}
+	l.Table.SparseRows = []int{}
+	l.Initialize(nil)
+	return l
+}
+
+// embeddingInit draws the default table values from N(0, 0.05^2)
+type embeddingInit struct{}
+
+func (embeddingInit) Initialize(rows, cols int, rng *rand.Rand) *mat.Dense {
+	return fillMatrix(rows, cols, func() float64 { return 0.05 * randNorm(rng) })
+}
+
+// Initialize redraws the table from TableInit using rng, or the global
+// math/rand source when rng is nil
+func (l *Embedding) Initialize(rng *rand.Rand) {
+	vocabSize, dimensions := l.Table.Value.Dims()
+	l.Table.Value.Copy(l.TableInit.Initialize(vocabSize, dimensions, rng))
+}
+
+// Dimensions returns the size of the embedding vectors
+func (l *Embedding) Dimensions() int {
+	_, dimensions := l.Table.Value.Dims()
+	return dimensions
+}
+
+// OutputSize returns the number of output columns
+func (l *Embedding) OutputSize() int {
+	return l.Inputs - 1 + l.Dimensions()
+}
+
+func (l *Embedding) outputWidth(inputs int) (int, error) {
+	if inputs != l.Inputs {
+		return 0, fmt.Errorf("%d inputs, expected %d", inputs, l.Inputs)
+	}
+	if l.Column < 0 || l.Column >= l.Inputs {
+		return 0, fmt.Errorf("ID column %d out of range [0, %d)", l.Column, l.Inputs)
+	}
+	return l.OutputSize(), nil
+}
+
+// checkID returns an error unless v is an integer ID inside the vocabulary
+func (l *Embedding) checkID(v float64) error {
+	vocabSize, _ := l.Table.Value.Dims()
+	if v != math.Trunc(v) || v < 0 || v >= float64(vocabSize) {
+		return fmt.Errorf("column %d holds %v, expected an ID in [0, %d)", l.Column, v, vocabSize)
+	}
+	return nil
+}
+
+func (l *Embedding) Forward(input *mat.Dense, training bool) *mat.Dense {
+	rows, _ := input.Dims()
+	dimensions := l.Dimensions()
+	output := mat.NewDense(rows, l.OutputSize(), nil)
+	l.ids = make([]int, rows)
+	for i := 0; i < rows; i++ {
+		in, out := input.RawRowView(i), output.RawRowView(i)
+		copy(out, in[:l.Column])
+		copy(out[l.Column+dimensions:], in[l.Column+1:])
+		if l.checkID(in[l.Column]) != nil {
+			l.ids[i] = -1
+			continue
+		}
+		l.ids[i] = int(in[l.Column])
+		copy(out[l.Column:], l.Table.Value.RawRowView(l.ids[i]))
+	}
+	return output
+}
+
+// Backward scatters the gradients of the embedding vectors into the rows
+// of the table used by the batch. The gradient of the ID column is zero.
+func (l *Embedding) Backward(outputGradient *mat.Dense) *mat.Dense {
+	rows, _ := outputGradient.Dims()
+	dimensions := l.Dimensions()
+
+	// Clear the rows touched by the previous batch only
+	for _, id := range l.Table.SparseRows {
+		row := l.Table.Grad.RawRowView(id)
+		for j := range row {
+			row[j] = 0
+		}
+	}
+	l.Table.SparseRows = l.Table.SparseRows[:0]
+	seen := make(map[int]bool)
+
+	inputGradient := mat.NewDense(rows, l.Inputs, nil)
+	for i := 0; i < rows; i++ {
+		g, in := outputGradient.RawRowView(i), inputGradient.RawRowView(i)
+		copy(in[:l.Column], g[:l.Column])
+		copy(in[l.Column+1:], g[l.Column+dimensions:])
+
+		id := l.ids[i]
+		if id < 0 {
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			l.Table.SparseRows = append(l.Table.SparseRows, id)
+		}
+		row := l.Table.Grad.RawRowView(id)
+		for j, v := range g[l.Column : l.Column+dimensions] {
+			row[j] += v
+		}
+	}
+	return inputGradient
+}
+
+// Parameters returns the embedding table
+func (l *Embedding) Parameters() []*Parameter {
+	return []*Parameter{l.Table}
+}
+
+// EmbedColumn makes the network read the given input column as IDs in
+// [0, vocabSize) through a new Embedding layer of the given dimensions,
+// placed in front of the existing layers. Columns keep their original
+// indices, so several ID columns can be embedded one call at a time. The
+// first dense layer is recreated for its wider input.
+func (nn *Network) EmbedColumn(column, vocabSize, dimensions int) error {
+	if column < 0 || column >= nn.InputNodes {
+		return fmt.Errorf("column %d out of range [0, %d)", column, nn.InputNodes)
+	}
+
+	// The leading embeddings are kept in decreasing column order, so each
+	// one sees its column at the original index
+	front, insert, width := 0, 0, nn.InputNodes
+	for ; front < len(nn.Layers); front++ {
+		embedding, ok := nn.Layers[front].(*Embedding)
+		if !ok {
+			break
+		}
+		if embedding.Column == column {
+			return fmt.Errorf("column %d is already embedded", column)
+		}
+		if embedding.Column > column {
+			insert = front + 1
+		}
+		width += embedding.Dimensions() - 1
+	}
+	if front == len(nn.Layers) {
+		return errors.New("the network has no layer after the embeddings")
+	}
+	dense, ok := nn.Layers[front].(*DenseLayer)
+	if !ok {
+		return fmt.Errorf("layer %d after the embeddings is not a DenseLayer", front)
+	}
+
+	embedding := NewEmbedding(nn.InputNodes, column, vocabSize, dimensions)
+	embedding.Initialize(nn.rng)
+	// Embeddings before the new one widen the rows it receives
+	for _, previous := range nn.Layers[:insert] {
+		embedding.Inputs += previous.(*Embedding).Dimensions() - 1
+	}
+	// and the new one widens the rows of the embeddings after it
+	for _, next := range nn.Layers[insert:front] {
+		next.(*Embedding).Inputs += dimensions - 1
+	}
+
+	_, outputs := dense.Weights.Value.Dims()
+	replacement := NewDenseLayer(width+dimensions-1, outputs, dense.Activation)
+	replacement.WeightInit, replacement.BiasInit = dense.WeightInit, dense.BiasInit
+	replacement.Initialize(nn.rng)
+	nn.Layers[front] = replacement
+
+	nn.Layers = append(nn.Layers[:insert], append([]Layer{embedding}, nn.Layers[insert:]...)...)
+	return nil
+}
+
+// checkIDs verifies the ID columns read by the leading embeddings
+func (nn *Network) checkIDs(X [][]float64) error {
+	for _, layer := range nn.Layers {
+		embedding, ok := layer.(*Embedding)
+		if !ok {
+			break
+		}
+		for i, row := range X {
+			if embedding.Column < len(row) {
+				if err := embedding.checkID(row[embedding.Column]); err != nil {
+					return fmt.Errorf("row %d: %w", i, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// MixedInputs builds network input rows from dense features followed by
+// integer ID columns, such as the output of utils.LabelEncoder.Transform.
+// dense may be nil when every input is an ID.
+func MixedInputs(dense [][]float64, ids ...[]int) ([][]float64, error) {
+	n := len(dense)
+	if dense == nil && len(ids) > 0 {
+		n = len(ids[0])
+	}
+	for c, column := range ids {
+		if len(column) != n {
+			return nil, fmt.Errorf("ID column %d has %d values, expected %d", c, len(column), n)
+		}
+	}
+	rows := make([][]float64, n)
+	for i := range rows {
+		var features []float64
+		if dense != nil {
+			features = dense[i]
+		}
+		row := make([]float64, 0, len(features)+len(ids))
+		row = append(row, features...)
+		for _, column := range ids {
+			row = append(row, float64(column[i]))
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
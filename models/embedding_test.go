@@ -0,0 +1,50 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPredictRejectsUnknownEmbeddingID(t *testing.T) {
+	mlp := NewMLPClassifier(2, []int{4}, 2, 0.1, rand.NewSource(1))
+	if err := mlp.EmbedColumn(1, 3, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range [][]float64{{0.5, 7}, {0.5, -1}, {0.5, 1.5}} {
+		if _, err := mlp.Predict(input); err == nil {
+			t.Errorf("Predict(%v): expected an error", input)
+		}
+		if _, err := mlp.PredictProba(input); err == nil {
+			t.Errorf("PredictProba(%v): expected an error", input)
+		}
+		if _, err := mlp.PredictBatch([][]float64{{0.5, 1}, input}); err == nil {
+			t.Errorf("PredictBatch with %v: expected an error", input)
+		}
+	}
+	if _, err := mlp.PredictProba([]float64{0.5, 2}); err != nil {
+		t.Errorf("known ID: %v", err)
+	}
+}
+
+func TestEmbeddingForwardInvalidID(t *testing.T) {
+	l := NewEmbedding(3, 1, 4, 2)
+	input := rowsToDense([][]float64{{1, 9, 2}, {3, 2, 4}})
+	output := l.Forward(input, true)
+
+	want := [][]float64{{1, 0, 0, 2}, {3, 0, 0, 4}}
+	copy(want[1][1:3], l.Table.Value.RawRowView(2))
+	for i, row := range want {
+		for j, v := range row {
+			if got := output.At(i, j); got != v {
+				t.Errorf("output[%d][%d] = %v, expected %v", i, j, got, v)
+			}
+		}
+	}
+
+	gradient := rowsToDense([][]float64{{1, 1, 1, 1}, {1, 1, 1, 1}})
+	l.Backward(gradient)
+	if len(l.Table.SparseRows) != 1 || l.Table.SparseRows[0] != 2 {
+		t.Errorf("got gradient rows %v, expected [2]", l.Table.SparseRows)
+	}
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// LayerGradientCheck compares the gradients of the parameters of one layer
+type LayerGradientCheck struct {
+	Layer            int    // Index of the layer
+	Type             string // Go type of the layer
+	Parameters       int    // Number of checked parameter values
+	MaxAbsoluteError float64
+	MaxRelativeError float64
+}
+
+// GradientCheckReport holds the outcome of GradientCheck for every layer
+// with parameters, and the largest relative error over all of them
+type GradientCheckReport struct {
+	Layers           []LayerGradientCheck
+	MaxRelativeError float64
+}
+
+// String formats the report as a table with one row per layer
+func (r *GradientCheckReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-5s %-22s %10s %12s %12s\n", "layer", "type", "params", "max abs", "max rel")
+	for _, l := range r.Layers {
+		fmt.Fprintf(&b, "%-5d %-22s %10d %12.3e %12.3e\n", l.Layer, l.Type, l.Parameters, l.MaxAbsoluteError, l.MaxRelativeError)
+	}
+	fmt.Fprintf(&b, "max relative error %.3e\n", r.MaxRelativeError)
+	return b.String()
+}
+
+// GradientCheck verifies the backward passes of layers and loss on one
+// batch. It computes the analytic gradient of every parameter with a
+// forward and a backward pass, then the central difference
+// (L(w+epsilon) - L(w-epsilon)) / 2epsilon obtained by perturbing each
+// parameter value in turn, and reports the largest errors per layer. The
+// relative error of a value is |analytic - numeric| / max(|analytic| +
+// |numeric|, 1e-6). The floor keeps the rounding noise of the difference,
+// about 1e-11 for a loss near 1, from dominating gradients that are zero
+// or nearly so. Errors below about 1e-4 mean the gradients agree. An
+// epsilon of 0 defaults to 1e-5.
+//
+// The layers run in training mode, so batch normalization uses the batch
+// statistics, and layers drawing random numbers are reseeded before every
+// pass so that all passes see the same dropout masks and noise. Each
+// random layer gets its own seed drawn from src, so two dropout layers
+// draw different masks; a nil src uses the global math/rand source. The
+// parameters, running statistics and generators of the layers are left as
+// they were, while the gradients hold the analytic values. The L2 penalty
+// of a network is not part of the checked loss.
+func GradientCheck(layers []Layer, loss Loss, X, Y [][]float64, epsilon float64, src rand.Source) (*GradientCheckReport, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("no layers to check")
+	}
+	if loss == nil {
+		return nil, errors.New("loss is nil")
+	}
+	if len(X) == 0 || len(X) != len(Y) {
+		return nil, fmt.Errorf("X has %d rows and Y %d, expected the same non-zero number", len(X), len(Y))
+	}
+	if epsilon == 0 {
+		epsilon = 1e-5
+	}
+	inputs, targets := rowsToDense(X), rowsToDense(Y)
+
+	// Running statistics change on every training pass and random layers
+	// consume their generators, so both are restored at the end
+	var saved [][]float64
+	for _, layer := range layers {
+		if stateful, ok := layer.(statefulLayer); ok {
+			for _, values := range stateful.state() {
+				saved = append(saved, append([]float64(nil), values...))
+			}
+		}
+	}
+	// Each random layer keeps its own seed for the whole check
+	type randomState struct {
+		layer randomLayer
+		rng   *rand.Rand // Generator to restore
+		seed  int64
+	}
+	var seeds *rand.Rand
+	if src != nil {
+		seeds = rand.New(src)
+	}
+	var randoms []randomState
+	for _, layer := range layers {
+		if random, ok := layer.(randomLayer); ok {
+			randoms = append(randoms, randomState{layer: random, rng: random.random(), seed: randInt63(seeds)})
+		}
+	}
+	defer func() {
+		i := 0
+		for _, layer := range layers {
+			if stateful, ok := layer.(statefulLayer); ok {
+				for _, values := range stateful.state() {
+					copy(values, saved[i])
+					i++
+				}
+			}
+		}
+		for _, r := range randoms {
+			r.layer.setRand(r.rng)
+		}
+	}()
+
+	forward := func() *mat.Dense {
+		for _, r := range randoms {
+			r.layer.setRand(rand.New(rand.NewSource(r.seed)))
+		}
+		outputs := inputs
+		for _, layer := range layers {
+			outputs = layer.Forward(outputs, true)
+		}
+		return outputs
+	}
+
+	outputs := forward()
+	if rows, cols := outputs.Dims(); rows != len(Y) || cols != len(Y[0]) {
+		return nil, fmt.Errorf("the layers output %dx%d values, Y is %dx%d", rows, cols, len(Y), len(Y[0]))
+	}
+	gradient := loss.Gradient(outputs, targets)
+	for i := len(layers) - 1; i >= 0; i-- {
+		gradient = layers[i].Backward(gradient)
+	}
+
+	report := &GradientCheckReport{}
+	for i, layer := range layers {
+		params := layer.Parameters()
+		if len(params) == 0 {
+			continue
+		}
+		check := LayerGradientCheck{Layer: i, Type: fmt.Sprintf("%T", layer)}
+		for _, p := range params {
+			value, grad := rawData(p.Value), rawData(p.Grad)
+			for j := range value {
+				original := value[j]
+				value[j] = original + epsilon
+				plus := loss.Loss(forward(), targets)
+				value[j] = original - epsilon
+				minus := loss.Loss(forward(), targets)
+				value[j] = original
+
+				numeric := (plus - minus) / (2 * epsilon)
+				absolute := math.Abs(grad[j] - numeric)
+				relative := absolute / math.Max(math.Abs(grad[j])+math.Abs(numeric), 1e-6)
+				check.MaxAbsoluteError = math.Max(check.MaxAbsoluteError, absolute)
+				check.MaxRelativeError = math.Max(check.MaxRelativeError, relative)
+				check.Parameters++
+			}
+		}
+		report.Layers = append(report.Layers, check)
+		report.MaxRelativeError = math.Max(report.MaxRelativeError, check.MaxRelativeError)
+	}
+	return report, nil
+}
+
+// gradientCheck runs GradientCheck on the layers of the network with the
+// given loss after checking the data like Fit. The seeds of the random
+// layers come from the generator of the network
+func (nn *Network) gradientCheck(loss Loss, X, Y [][]float64, epsilon float64) (*GradientCheckReport, error) {
+	if err := nn.checkData(X, Y); err != nil {
+		return nil, err
+	}
+	var src rand.Source
+	if nn.rng != nil {
+		src = nn.rng
+	}
+	return GradientCheck(nn.Layers, loss, X, Y, epsilon, src)
+}
+
+// GradientCheck runs GradientCheck on the layers of the classifier with
+// its training loss
+func (mlp *MLPClassifier) GradientCheck(X, Y [][]float64, epsilon float64) (*GradientCheckReport, error) {
+	return mlp.gradientCheck(mlp.loss(), X, Y, epsilon)
+}
+
+// GradientCheck runs GradientCheck on the layers of the regressor with
+// its training loss
+func (mlp *MLPRegressor) GradientCheck(X, Y [][]float64, epsilon float64) (*GradientCheckReport, error) {
+	return mlp.gradientCheck(mlp.loss(), X, Y, epsilon)
+}
+
+// GradientCheck runs GradientCheck on the layers of the model with its
+// training loss
+func (s *Sequential) GradientCheck(X, Y [][]float64, epsilon float64) (*GradientCheckReport, error) {
+	if err := s.inferOutputs(); err != nil {
+		return nil, err
+	}
+	return s.gradientCheck(s.loss(), X, Y, epsilon)
+}
+
+// GradientCheck runs GradientCheck on the layers of the autoencoder with
+// its training loss, using X as the targets
+func (ae *Autoencoder) GradientCheck(X [][]float64, epsilon float64) (*GradientCheckReport, error) {
+	return ae.gradientCheck(ae.loss(), X, X, epsilon)
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// gradientCheckTargets returns targets suited to loss: one-hot rows for
+// the softmax, 0/1 values for the sigmoid and real values otherwise
+func gradientCheckTargets(rng *rand.Rand, loss Loss, n, cols int) [][]float64 {
+	Y := make([][]float64, n)
+	for i := range Y {
+		Y[i] = make([]float64, cols)
+		switch loss.(type) {
+		case SoftmaxCrossEntropy:
+			Y[i][rng.Intn(cols)] = 1
+		case SigmoidBinaryCrossEntropy:
+			for j := range Y[i] {
+				Y[i][j] = float64(rng.Intn(2))
+			}
+		default:
+			for j := range Y[i] {
+				Y[i][j] = rng.NormFloat64()
+			}
+		}
+	}
+	return Y
+}
+
+func TestGradientCheckLayers(t *testing.T) {
+	const outputs = 3
+	cases := []struct {
+		name   string
+		inputs int
+		layers func() []Layer
+		ids    bool // Column 1 holds embedding IDs in [0, 5)
+	}{
+		{"Dense", 4, func() []Layer {
+			return []Layer{NewDenseLayer(4, 5, Tanh{}), NewDenseLayer(5, outputs, Identity{})}
+		}, false},
+		{"BatchNorm", 4, func() []Layer {
+			return []Layer{NewDenseLayer(4, 5, Identity{}), NewBatchNormLayer(5), NewActivationLayer(Tanh{}), NewDenseLayer(5, outputs, Identity{})}
+		}, false},
+		{"Dropout", 4, func() []Layer {
+			return []Layer{NewDenseLayer(4, 6, Tanh{}), &DropoutLayer{Rate: 0.3}, NewGaussianNoiseLayer(0.1), NewDenseLayer(6, outputs, Identity{})}
+		}, false},
+		{"Conv2D", 4 * 4 * 2, func() []Layer {
+			conv := NewConv2D(4, 4, 2, 3, 3, 1, 1, Tanh{})
+			h, w, c := conv.OutputShape()
+			return []Layer{conv, NewFlatten(), NewDenseLayer(h*w*c, outputs, Identity{})}
+		}, false},
+		{"MaxPool2D", 4 * 4 * 2, func() []Layer {
+			conv := NewConv2D(4, 4, 2, 3, 3, 1, 1, Tanh{})
+			pool := NewMaxPool2D(4, 4, 3, 2, 0)
+			h, w, c := pool.OutputShape()
+			return []Layer{conv, pool, NewFlatten(), NewDenseLayer(h*w*c, outputs, Identity{})}
+		}, false},
+		{"AvgPool2D", 4 * 4 * 2, func() []Layer {
+			conv := NewConv2D(4, 4, 2, 3, 3, 1, 1, Tanh{})
+			pool := NewAvgPool2D(4, 4, 3, 2, 0)
+			h, w, c := pool.OutputShape()
+			return []Layer{conv, pool, NewFlatten(), NewDenseLayer(h*w*c, outputs, Identity{})}
+		}, false},
+		{"SimpleRNN", 3 * 2, func() []Layer {
+			return []Layer{NewSimpleRNN(3, 2, 4, false), NewDenseLayer(4, outputs, Identity{})}
+		}, false},
+		{"GRU", 3 * 2, func() []Layer {
+			return []Layer{NewGRU(3, 2, 4, true), NewDenseLayer(3*4, outputs, Identity{})}
+		}, false},
+		{"LSTM", 3 * 2, func() []Layer {
+			return []Layer{NewLSTM(3, 2, 4, false), NewDenseLayer(4, outputs, Identity{})}
+		}, false},
+		{"Embedding", 3, func() []Layer {
+			return []Layer{NewEmbedding(3, 1, 5, 2), NewDenseLayer(4, outputs, Tanh{}), NewDenseLayer(outputs, outputs, Identity{})}
+		}, true},
+	}
+	losses := []Loss{SoftmaxCrossEntropy{}, SigmoidBinaryCrossEntropy{}, MeanSquaredError{}, MeanAbsoluteError{}, Huber{}}
+
+	for _, c := range cases {
+		for _, loss := range losses {
+			rng := rand.New(rand.NewSource(1))
+			X := gradientCheckRows(rng, 6, c.inputs)
+			if c.ids {
+				for i := range X {
+					X[i][1] = float64(i % 5)
+				}
+			}
+			Y := gradientCheckTargets(rng, loss, 6, outputs)
+			layers := c.layers()
+			for _, layer := range layers {
+				if l, ok := layer.(initializableLayer); ok {
+					l.Initialize(rng)
+				}
+			}
+
+			report, err := GradientCheck(layers, loss, X, Y, 0, rand.NewSource(1))
+			if err != nil {
+				t.Fatalf("%s with %T: %v", c.name, loss, err)
+			}
+			if report.MaxRelativeError > 1e-4 {
+				t.Errorf("%s with %T: max relative error %.3e\n%s", c.name, loss, report.MaxRelativeError, report)
+			}
+		}
+	}
+}
+
+func TestGradientCheckSeedsRandomLayersApart(t *testing.T) {
+	first, second := &DropoutLayer{Rate: 0.5}, &DropoutLayer{Rate: 0.5}
+	layers := []Layer{first, second, NewDenseLayer(20, 2, Identity{})}
+	X := make([][]float64, 4)
+	for i := range X {
+		X[i] = make([]float64, 20)
+		for j := range X[i] {
+			X[i][j] = 1
+		}
+	}
+	if _, err := GradientCheck(layers, MeanSquaredError{}, X, gradientCheckRows(rand.New(rand.NewSource(1)), 4, 2), 0, rand.NewSource(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second layer drops a subset of what the first one kept; with
+	// the same seed it would draw the same mask and drop nothing more
+	zeros := func(l *DropoutLayer) int {
+		n := 0
+		for _, v := range rawData(l.tape.output.Value) {
+			if v == 0 {
+				n++
+			}
+		}
+		return n
+	}
+	if zeros(second) == zeros(first) {
+		t.Errorf("both dropout layers drew the same mask")
+	}
+}
+
+func TestGradientCheckSeedsFromSource(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	X, Y := gradientCheckRows(rng, 4, 5), gradientCheckRows(rng, 4, 2)
+	layers := []Layer{NewDenseLayer(5, 6, Tanh{}), &DropoutLayer{Rate: 0.5}, NewDenseLayer(6, 2, Identity{})}
+	check := func(seed int64) []float64 {
+		if _, err := GradientCheck(layers, MeanSquaredError{}, X, Y, 0, rand.NewSource(seed)); err != nil {
+			t.Fatal(err)
+		}
+		return append([]float64(nil), rawData(layers[0].Parameters()[0].Grad)...)
+	}
+	first, second, other := check(3), check(3), check(4)
+	same := func(a, b []float64) bool {
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	if !same(first, second) {
+		t.Error("the same source gave different dropout masks")
+	}
+	if same(first, other) {
+		t.Error("different sources gave the same dropout masks")
+	}
+}
+
+func TestModelGradientChecks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	X := gradientCheckRows(rng, 5, 4)
+	Y := gradientCheckTargets(rng, SoftmaxCrossEntropy{}, 5, 3)
+	regression := gradientCheckTargets(rng, MeanSquaredError{}, 5, 2)
+
+	type result struct {
+		name   string
+		report *GradientCheckReport
+		err    error
+	}
+	var results []result
+	add := func(name string, report *GradientCheckReport, err error) {
+		results = append(results, result{name, report, err})
+	}
+	classifier := NewMLPClassifier(4, []int{5}, 3, 0.1, rand.NewSource(2))
+	report, err := classifier.GradientCheck(X, Y, 0)
+	add("MLPClassifier", report, err)
+	regressor := NewMLPRegressor(4, []int{5}, 2, 0.1, rand.NewSource(3))
+	report, err = regressor.GradientCheck(X, regression, 0)
+	add("MLPRegressor", report, err)
+	sequential := NewSequential(4, 0.1, rand.NewSource(4), NewDenseLayer(4, 5, Tanh{}), &DropoutLayer{Rate: 0.2}, NewDenseLayer(5, 3, Identity{}))
+	report, err = sequential.GradientCheck(X, Y, 0)
+	add("Sequential", report, err)
+	autoencoder := NewAutoencoder(4, []int{3}, 2, 0.1, rand.NewSource(5))
+	report, err = autoencoder.GradientCheck(X, 0)
+	add("Autoencoder", report, err)
+
+	for _, r := range results {
+		if r.err != nil {
+			t.Errorf("%s: %v", r.name, r.err)
+			continue
+		}
+		if r.report.MaxRelativeError > 1e-4 {
+			t.Errorf("%s: max relative error %.3e\n%s", r.name, r.report.MaxRelativeError, r.report)
+		}
+	}
+
+	if _, err := regressor.GradientCheck(X, Y, 0); err == nil {
+		t.Error("MLPRegressor: expected an error for 3 targets on 2 outputs")
+	}
+	if _, err := sequential.GradientCheck([][]float64{{1, 2}}, Y[:1], 0); err == nil {
+		t.Error("Sequential: expected an error for 2 features")
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+// History records the metrics of every training epoch. The validation
+// slices stay empty when no validation split is used.
+type History struct {
+	Loss               []float64
+	Accuracy           []float64
+	ValidationLoss     []float64
+	ValidationAccuracy []float64
+
+	BestEpoch int  // Epoch with the lowest monitored loss; -1 if none
+	Stopped   bool // Whether early stopping or a callback ended training before the last epoch
+}
+
+// Epochs returns the number of epochs actually run
+func (h *History) Epochs() int {
+	return len(h.Loss)
+}
+
+// earlyStopper tracks the best monitored loss and the number of epochs
+// since it last improved by more than minDelta
+type earlyStopper struct {
+	patience  int
+	minDelta  float64
+	bestLoss  float64
+	badEpochs int
+}
+
+// improved records a new loss and reports whether it is the best so far
+func (s *earlyStopper) improved(loss float64) bool {
+	if loss < s.bestLoss-s.minDelta {
+		s.bestLoss = loss
+		s.badEpochs = 0
+		return true
+	}
+	s.badEpochs++
+	return false
+}
+
+// shouldStop reports whether the loss has not improved for patience
+// epochs (or for one epoch when patience is 0)
+func (s *earlyStopper) shouldStop() bool {
+	return s.badEpochs > 0 && s.badEpochs >= s.patience
+}
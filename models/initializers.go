@@ -0,0 +1,143 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Initializer fills a new rows x cols parameter matrix. For dense weights
+// rows is the fan-in and cols the fan-out. A nil rng uses the global
+// math/rand source.
+type Initializer interface {
+	Initialize(rows, cols int, rng *rand.Rand) *mat.Dense
+}
+
+// RandomUniform draws every value uniformly from [Min, Max)
+type RandomUniform struct {
+	Min, Max float64
+}
+
+func (u RandomUniform) Initialize(rows, cols int, rng *rand.Rand) *mat.Dense {
+	return fillMatrix(rows, cols, func() float64 {
+		return u.Min + (u.Max-u.Min)*randFloat64(rng)
+	})
+}
+
+// XavierUniform (Glorot) draws from U(-l, l) with l = sqrt(6/(fanIn+fanOut)),
+// keeping the activation variance stable for sigmoid and tanh layers
+type XavierUniform struct{}
+
+func (XavierUniform) Initialize(rows, cols int, rng *rand.Rand) *mat.Dense {
+	limit := math.Sqrt(6 / float64(rows+cols))
+	return RandomUniform{Min: -limit, Max: limit}.Initialize(rows, cols, rng)
+}
+
+// XavierNormal (Glorot) draws from N(0, 2/(fanIn+fanOut))
+type XavierNormal struct{}
+
+func (XavierNormal) Initialize(rows, cols int, rng *rand.Rand) *mat.Dense {
+	std := math.Sqrt(2 / float64(rows+cols))
+	return fillMatrix(rows, cols, func() float64 { return std * randNorm(rng) })
+}
+
+// HeUniform (Kaiming) draws from U(-l, l) with l = sqrt(6/fanIn), suited to
+// ReLU layers
+type HeUniform struct{}
+
+func (HeUniform) Initialize(rows, cols int, rng *rand.Rand) *mat.Dense {
+	limit := math.Sqrt(6 / float64(rows))
+	return RandomUniform{Min: -limit, Max: limit}.Initialize(rows, cols, rng)
+}
+
+// HeNormal (Kaiming) draws from N(0, 2/fanIn)
+type HeNormal struct{}
+
+func (HeNormal) Initialize(rows, cols int, rng *rand.Rand) *mat.Dense {
+	std := math.Sqrt(2 / float64(rows))
+	return fillMatrix(rows, cols, func() float64 { return std * randNorm(rng) })
+}
+
+// Orthogonal produces a (semi-)orthogonal matrix scaled by Gain, from the
+// QR decomposition of a Gaussian matrix. A zero Gain defaults to 1.
+type Orthogonal struct {
+	Gain float64
+}
+
+func (o Orthogonal) Initialize(rows, cols int, rng *rand.Rand) *mat.Dense {
+	gain := o.Gain
+	if gain == 0 {
+		gain = 1
+	}
+
+	// QR needs at least as many rows as columns, so factorize the
+	// transpose of wide matrices
+	m, n := rows, cols
+	if m < n {
+		m, n = n, m
+	}
+	a := fillMatrix(m, n, func() float64 { return randNorm(rng) })
+	var qr mat.QR
+	qr.Factorize(a)
+	var q, r mat.Dense
+	qr.QTo(&q)
+	qr.RTo(&r)
+
+	// Fixing the signs with the diagonal of R makes the distribution uniform
+	result := mat.NewDense(m, n, nil)
+	for j := 0; j < n; j++ {
+		sign := 1.0
+		if r.At(j, j) < 0 {
+			sign = -1
+		}
+		for i := 0; i < m; i++ {
+			result.Set(i, j, gain*sign*q.At(i, j))
+		}
+	}
+	if rows < cols {
+		return mat.DenseCopyOf(result.T())
+	}
+	return result
+}
+
+// Zeros fills the matrix with zeros, the usual choice for biases
+type Zeros struct{}
+
+func (Zeros) Initialize(rows, cols int, rng *rand.Rand) *mat.Dense {
+	return mat.NewDense(rows, cols, nil)
+}
+
+// fillMatrix builds a matrix whose values are drawn one by one from next
+func fillMatrix(rows, cols int, next func() float64) *mat.Dense {
+	data := make([]float64, rows*cols)
+	for i := range data {
+		data[i] = next()
+	}
+	return mat.NewDense(rows, cols, data)
+}
+
+// randFloat64 draws from [0, 1) using rng, or the global source when nil
+func randFloat64(rng *rand.Rand) float64 {
+	if rng == nil {
+		return rand.Float64()
+	}
+	return rng.Float64()
+}
+
+// randNorm draws from N(0, 1) using rng, or the global source when nil
+func randNorm(rng *rand.Rand) float64 {
+	if rng == nil {
+		return rand.NormFloat64()
+	}
+	return rng.NormFloat64()
+}
+
+// randInt63 draws a non-negative int64 using rng, or the global source
+// when nil
+func randInt63(rng *rand.Rand) int64 {
+	if rng == nil {
+		return rand.Int63()
+	}
+	return rng.Int63()
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// matrixMoments returns the mean and variance of the values of a matrix
+func matrixMoments(m *mat.Dense) (float64, float64) {
+	data := rawData(m)
+	mean := 0.0
+	for _, v := range data {
+		mean += v
+	}
+	mean /= float64(len(data))
+	variance := 0.0
+	for _, v := range data {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(data))
+}
+
+func TestInitializerVariance(t *testing.T) {
+	const fanIn, fanOut = 400, 300
+	glorot := 2.0 / (fanIn + fanOut)
+	he := 2.0 / fanIn
+	tests := []struct {
+		init     Initializer
+		variance float64
+	}{
+		{XavierUniform{}, glorot},
+		{XavierNormal{}, glorot},
+		{HeUniform{}, he},
+		{HeNormal{}, he},
+		{RandomUniform{Min: -1, Max: 1}, 1.0 / 3},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		mean, variance := matrixMoments(tt.init.Initialize(fanIn, fanOut, rng))
+		if math.Abs(mean) > 0.05*math.Sqrt(tt.variance) {
+			t.Errorf("%T: mean %v, expected about 0", tt.init, mean)
+		}
+		if math.Abs(variance/tt.variance-1) > 0.03 {
+			t.Errorf("%T: variance %v, expected %v", tt.init, variance, tt.variance)
+		}
+	}
+
+	for _, v := range rawData(Zeros{}.Initialize(3, 4, rng)) {
+		if v != 0 {
+			t.Fatalf("Zeros produced %v", v)
+		}
+	}
+}
+
+func TestOrthogonalInitializer(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, shape := range [][2]int{{8, 5}, {5, 8}, {6, 6}} {
+		rows, cols := shape[0], shape[1]
+		w := Orthogonal{Gain: 2}.Initialize(rows, cols, rng)
+		if r, c := w.Dims(); r != rows || c != cols {
+			t.Fatalf("%dx%d: got a %dx%d matrix", rows, cols, r, c)
+		}
+		// The shorter side is orthonormal up to the gain: WᵀW or WWᵀ is 4I
+		var product mat.Dense
+		if rows >= cols {
+			product.Mul(w.T(), w)
+		} else {
+			product.Mul(w, w.T())
+		}
+		n, _ := product.Dims()
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				want := 0.0
+				if i == j {
+					want = 4
+				}
+				if math.Abs(product.At(i, j)-want) > 1e-12 {
+					t.Errorf("%dx%d: product[%d][%d] is %v, expected %v", rows, cols, i, j, product.At(i, j), want)
+				}
+			}
+		}
+	}
+}
+
+func TestSameSeedTrainsIdentically(t *testing.T) {
+	X, Y := xorData()
+	train := func(seed int64) (*MLPClassifier, *History) {
+		mlp := NewMLPClassifier(2, []int{6, 4}, 2, 0.3, rand.NewSource(seed))
+		if err := mlp.SetInitializer(1, HeNormal{}, nil); err != nil {
+			t.Fatal(err)
+		}
+		mlp.Layers = append(mlp.Layers[:1], append([]Layer{&DropoutLayer{Rate: 0.2}}, mlp.Layers[1:]...)...)
+		mlp.BatchSize = 2
+		history, err := mlp.Fit(X, Y, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mlp, history
+	}
+
+	first, firstHistory := train(3)
+	second, secondHistory := train(3)
+	if !reflect.DeepEqual(firstHistory, secondHistory) {
+		t.Errorf("histories differ:\n%+v\n%+v", firstHistory, secondHistory)
+	}
+	for i, p := range first.parameters() {
+		if !mat.Equal(p.Value, second.parameters()[i].Value) {
+			t.Errorf("parameter %d differs between runs with the same seed", i)
+		}
+	}
+
+	other, _ := train(4)
+	if mat.Equal(first.parameters()[0].Value, other.parameters()[0].Value) {
+		t.Error("different seeds gave the same weights")
+	}
+}
@@ -0,0 +1,400 @@
+package models
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/snugml/go/autograd"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Layer is one stage of a feed-forward network working on mini-batches
+// with one sample per row. Forward caches whatever Backward needs and
+// behaves differently while training for layers such as dropout;
+// Backward receives the gradient of the loss with respect to the layer
+// output, stores the parameter gradients and returns the gradient with
+// respect to the layer input. Parameters exposes the trainable matrices
+// to the optimizer.
+type Layer interface {
+	Forward(input *mat.Dense, training bool) *mat.Dense
+	Backward(outputGradient *mat.Dense) *mat.Dense
+	Parameters() []*Parameter
+}
+
+// tape records the autograd graph of the last forward pass of a layer, so
+// that Backward only has to differentiate it instead of using hand-derived
+// gradients
+type tape struct {
+	input  *autograd.Variable
+	params []*Parameter
+	vars   []*autograd.Variable // One leaf per parameter, sharing its value
+	output *autograd.Variable
+}
+
+// newTape creates the leaves for the input and the parameters of a forward
+// pass. Gradients are only tracked while training.
+func newTape(input *mat.Dense, training bool, params ...*Parameter) *tape {
+	t := &tape{input: autograd.NewVariable(input, training), params: params}
+	for _, p := range params {
+		t.vars = append(t.vars, autograd.NewVariable(p.Value, training))
+	}
+	return t
+}
+
+// backward differentiates the recorded graph, stores the parameter
+// gradients and returns the gradient with respect to the input
+func (t *tape) backward(outputGradient *mat.Dense) *mat.Dense {
+	t.input.ZeroGrad()
+	for _, v := range t.vars {
+		v.ZeroGrad()
+	}
+	t.output.BackwardWith(outputGradient)
+	for i, p := range t.params {
+		if g := t.vars[i].Grad; g != nil {
+			p.Grad.Copy(g)
+		} else {
+			p.Grad.Zero()
+		}
+	}
+	if t.input.Grad == nil {
+		rows, cols := t.input.Dims()
+		return mat.NewDense(rows, cols, nil)
+	}
+	return t.input.Grad
+}
+
+// applyActivation maps an Activation over a Variable
+func applyActivation(x *autograd.Variable, activation Activation) *autograd.Variable {
+	return autograd.Apply(x, activation.Activate, activation.Derivative)
+}
+
+// DenseLayer is a fully connected layer with its own weights, activation
+// and gradients
+type DenseLayer struct {
+	Weights *Parameter // inputs x outputs
+	Bias    *Parameter // 1 x outputs
+
+	Activation Activation
+
+	// WeightInit and BiasInit fill the parameters when the layer is
+	// created or reinitialized
+	WeightInit Initializer
+	BiasInit   Initializer
+
+	tape *tape
+}
+
+// NewDenseLayer creates a layer with Xavier uniform weights and zero
+// biases drawn from the global math/rand source; call Initialize to draw
+// them from a seeded generator. A nil activation defaults to Sigmoid
+func NewDenseLayer(inputs, outputs int, activation Activation) *DenseLayer {
+	if activation == nil {
+		activation = Sigmoid{}
+	}
+	l := &DenseLayer{
+		Weights:    newParameter(mat.NewDense(inputs, outputs, nil)),
+		Bias:       newParameter(mat.NewDense(1, outputs, nil)),
+		Activation: activation,
+		WeightInit: XavierUniform{},
+		BiasInit:   Zeros{},
+	}
+	l.Weights.Regularize = true
+	l.Initialize(nil)
+	return l
+}
+
+// Initialize redraws the weights and biases from WeightInit and BiasInit
+// using rng, or the global math/rand source when rng is nil
+func (l *DenseLayer) Initialize(rng *rand.Rand) {
+	inputs, outputs := l.Weights.Value.Dims()
+	l.Weights.Value.Copy(l.WeightInit.Initialize(inputs, outputs, rng))
+	l.Bias.Value.Copy(l.BiasInit.Initialize(1, outputs, rng))
+}
+
+// Forward computes activation(input * Weights + Bias) for every row
+func (l *DenseLayer) Forward(input *mat.Dense, training bool) *mat.Dense {
+	rows, _ := input.Dims()
+	t := newTape(input, training, l.Weights, l.Bias)
+	preOutput := autograd.Add(autograd.MatMul(t.input, t.vars[0]), autograd.BroadcastRows(t.vars[1], rows))
+	t.output = applyActivation(preOutput, l.Activation)
+	l.tape = t
+	return t.output.Value
+}
+
+// Backward stores the weight and bias gradients and propagates the
+// gradient to the previous layer
+func (l *DenseLayer) Backward(outputGradient *mat.Dense) *mat.Dense {
+	return l.tape.backward(outputGradient)
+}
+
+// Parameters returns the weights and the bias
+func (l *DenseLayer) Parameters() []*Parameter {
+	return []*Parameter{l.Weights, l.Bias}
+}
+
+func (l *DenseLayer) outputWidth(inputs int) (int, error) {
+	rows, outputs := l.Weights.Value.Dims()
+	if inputs != rows {
+		return 0, fmt.Errorf("%d inputs, expected %d", inputs, rows)
+	}
+	return outputs, nil
+}
+
+// initializableLayer is implemented by layers whose parameters can be
+// redrawn from a random generator
+type initializableLayer interface {
+	Initialize(rng *rand.Rand)
+}
+
+// randomLayer is implemented by layers that draw random numbers while
+// training, so the network can hand them its seeded generator
+type randomLayer interface {
+	setRand(rng *rand.Rand)
+	random() *rand.Rand
+}
+
+// shapedLayer is implemented by layers that accept rows of a fixed width
+// or whose settings can be invalid. outputWidth returns the width of the
+// layer output for input rows of the given width, or an error, so that a
+// mismatched stack is reported before it reaches autograd.
+type shapedLayer interface {
+	outputWidth(inputs int) (int, error)
+}
+
+// statefulLayer is implemented by layers that hold non-trainable state,
+// such as running statistics, which must be saved and restored together
+// with their parameters
+type statefulLayer interface {
+	state() [][]float64
+}
+
+// ActivationLayer applies an activation on its own, so that layers such as
+// batch normalization can sit between a linear DenseLayer and its
+// non-linearity
+type ActivationLayer struct {
+	Activation Activation
+
+	tape *tape
+}
+
+// NewActivationLayer creates a layer applying the given activation
+func NewActivationLayer(activation Activation) *ActivationLayer {
+	return &ActivationLayer{Activation: activation}
+}
+
+func (l *ActivationLayer) Forward(input *mat.Dense, training bool) *mat.Dense {
+	t := newTape(input, training)
+	t.output = applyActivation(t.input, l.Activation)
+	l.tape = t
+	return t.output.Value
+}
+
+func (l *ActivationLayer) Backward(outputGradient *mat.Dense) *mat.Dense {
+	return l.tape.backward(outputGradient)
+}
+
+func (l *ActivationLayer) Parameters() []*Parameter { return nil }
+
+// DropoutLayer zeroes each input with probability Rate while training and
+// scales the survivors by 1/(1-Rate), so it is a no-op at inference time
+type DropoutLayer struct {
+	Rate float64
+
+	tape *tape      // nil when the last forward pass was not training
+	rng  *rand.Rand // nil uses the global math/rand source
+}
+
+// NewDropoutLayer creates a dropout layer with the given drop probability,
+// which must be in [0, 1)
+func NewDropoutLayer(rate float64) (*DropoutLayer, error) {
+	if err := checkDropoutRate(rate); err != nil {
+		return nil, err
+	}
+	return &DropoutLayer{Rate: rate}, nil
+}
+
+// checkDropoutRate rejects rates that would drop every input: the
+// survivors would be scaled by 1/0
+func checkDropoutRate(rate float64) error {
+	if !(rate >= 0 && rate < 1) {
+		return fmt.Errorf("dropout rate is %v, expected a value in [0, 1)", rate)
+	}
+	return nil
+}
+
+func (l *DropoutLayer) Forward(input *mat.Dense, training bool) *mat.Dense {
+	if !training || l.Rate <= 0 {
+		l.tape = nil
+		return input
+	}
+	rows, cols := input.Dims()
+	keep := 1 - l.Rate
+	mask := mat.NewDense(rows, cols, nil)
+	mask.Apply(func(_, _ int, _ float64) float64 {
+		if randFloat64(l.rng) < keep {
+			return 1 / keep
+		}
+		return 0
+	}, mask)
+
+	t := newTape(input, training)
+	t.output = autograd.Mul(t.input, autograd.Constant(mask))
+	l.tape = t
+	return t.output.Value
+}
+
+func (l *DropoutLayer) Backward(outputGradient *mat.Dense) *mat.Dense {
+	if l.tape == nil {
+		return outputGradient
+	}
+	return l.tape.backward(outputGradient)
+}
+
+func (l *DropoutLayer) Parameters() []*Parameter { return nil }
+
+func (l *DropoutLayer) outputWidth(inputs int) (int, error) {
+	return inputs, checkDropoutRate(l.Rate)
+}
+
+func (l *DropoutLayer) setRand(rng *rand.Rand) { l.rng = rng }
+func (l *DropoutLayer) random() *rand.Rand     { return l.rng }
+
+// GaussianNoiseLayer adds zero-mean Gaussian noise with standard deviation
+// StdDev to its input while training and is a no-op at inference time,
+// which makes a network learn to undo the corruption
+type GaussianNoiseLayer struct {
+	StdDev float64
+
+	tape *tape      // nil when the last forward pass was not training
+	rng  *rand.Rand // nil uses the global math/rand source
+}
+
+// NewGaussianNoiseLayer creates a noise layer with the given standard
+// deviation
+func NewGaussianNoiseLayer(stdDev float64) *GaussianNoiseLayer {
+	return &GaussianNoiseLayer{StdDev: stdDev}
+}
+
+func (l *GaussianNoiseLayer) Forward(input *mat.Dense, training bool) *mat.Dense {
+	if !training || l.StdDev <= 0 {
+		l.tape = nil
+		return input
+	}
+	rows, cols := input.Dims()
+	noise := fillMatrix(rows, cols, func() float64 { return l.StdDev * randNorm(l.rng) })
+
+	t := newTape(input, training)
+	t.output = autograd.Add(t.input, autograd.Constant(noise))
+	l.tape = t
+	return t.output.Value
+}
+
+func (l *GaussianNoiseLayer) Backward(outputGradient *mat.Dense) *mat.Dense {
+	if l.tape == nil {
+		return outputGradient
+	}
+	return l.tape.backward(outputGradient)
+}
+
+func (l *GaussianNoiseLayer) Parameters() []*Parameter { return nil }
+
+func (l *GaussianNoiseLayer) setRand(rng *rand.Rand) { l.rng = rng }
+func (l *GaussianNoiseLayer) random() *rand.Rand     { return l.rng }
+
+// BatchNormLayer normalizes every feature with the statistics of the
+// current mini-batch while training, then scales by Gamma and shifts by
+// Beta. Running averages of the batch statistics replace them at
+// inference time.
+type BatchNormLayer struct {
+	Gamma *Parameter // 1 x features
+	Beta  *Parameter // 1 x features
+
+	Momentum float64 // Weight of the old running statistics in each update
+	Epsilon  float64
+
+	RunningMean []float64
+	RunningVar  []float64
+
+	tape *tape
+}
+
+// NewBatchNormLayer creates a batch normalization layer for the given
+// number of features, with momentum 0.9 and epsilon 1e-5
+func NewBatchNormLayer(features int) *BatchNormLayer {
+	gamma := mat.NewDense(1, features, nil)
+	runningVar := make([]float64, features)
+	for j := 0; j < features; j++ {
+		gamma.Set(0, j, 1)
+		runningVar[j] = 1
+	}
+	return &BatchNormLayer{
+		Gamma:       newParameter(gamma),
+		Beta:        newParameter(mat.NewDense(1, features, nil)),
+		Momentum:    0.9,
+		Epsilon:     1e-5,
+		RunningMean: make([]float64, features),
+		RunningVar:  runningVar,
+	}
+}
+
+func (l *BatchNormLayer) Forward(input *mat.Dense, training bool) *mat.Dense {
+	rows, cols := input.Dims()
+	t := newTape(input, training, l.Gamma, l.Beta)
+	var mean, variance *autograd.Variable
+	if training {
+		mean = autograd.ColumnMean(t.input)
+		centered := autograd.Sub(t.input, autograd.BroadcastRows(mean, rows))
+		variance = autograd.ColumnMean(autograd.Square(centered))
+		l.updateRunningStats(mean.Value.RawRowView(0), variance.Value.RawRowView(0), rows)
+	} else {
+		mean = autograd.Constant(mat.NewDense(1, cols, append([]float64(nil), l.RunningMean...)))
+		variance = autograd.Constant(mat.NewDense(1, cols, append([]float64(nil), l.RunningVar...)))
+	}
+
+	stdDev := autograd.Sqrt(autograd.AddScalar(variance, l.Epsilon))
+	normalized := autograd.Div(
+		autograd.Sub(t.input, autograd.BroadcastRows(mean, rows)),
+		autograd.BroadcastRows(stdDev, rows),
+	)
+	t.output = autograd.Add(
+		autograd.Mul(normalized, autograd.BroadcastRows(t.vars[0], rows)),
+		autograd.BroadcastRows(t.vars[1], rows),
+	)
+	l.tape = t
+	return t.output.Value
+}
+
+// updateRunningStats folds the statistics of a training batch into the
+// running averages. The running variance uses the unbiased estimate
+func (l *BatchNormLayer) updateRunningStats(mean, variance []float64, rows int) {
+	n := float64(rows)
+	for j := range mean {
+		unbiased := variance[j]
+		if rows > 1 {
+			unbiased *= n / (n - 1)
+		}
+		l.RunningMean[j] = l.Momentum*l.RunningMean[j] + (1-l.Momentum)*mean[j]
+		l.RunningVar[j] = l.Momentum*l.RunningVar[j] + (1-l.Momentum)*unbiased
+	}
+}
+
+func (l *BatchNormLayer) Backward(outputGradient *mat.Dense) *mat.Dense {
+	return l.tape.backward(outputGradient)
+}
+
+// Parameters returns the scale and the shift
+func (l *BatchNormLayer) Parameters() []*Parameter {
+	return []*Parameter{l.Gamma, l.Beta}
+}
+
+func (l *BatchNormLayer) outputWidth(inputs int) (int, error) {
+	if inputs != len(l.RunningMean) {
+		return 0, fmt.Errorf("%d inputs, expected %d features", inputs, len(l.RunningMean))
+	}
+	return inputs, nil
+}
+
+// state returns the running statistics so they are saved with the weights
+func (l *BatchNormLayer) state() [][]float64 {
+	return [][]float64{l.RunningMean, l.RunningVar}
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNewDropoutLayerRejectsRates(t *testing.T) {
+	for _, rate := range []float64{-0.1, 1, 1.5, math.NaN()} {
+		if _, err := NewDropoutLayer(rate); err == nil {
+			t.Errorf("rate %v: expected an error", rate)
+		}
+	}
+	if _, err := NewDropoutLayer(0); err != nil {
+		t.Errorf("rate 0: %v", err)
+	}
+}
+
+func TestFitRejectsDropoutRate(t *testing.T) {
+	X, Y := xorData()
+	mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, nil)
+	mlp.Layers = append(mlp.Layers[:1], append([]Layer{&DropoutLayer{Rate: 1}}, mlp.Layers[1:]...)...)
+	if _, err := mlp.Fit(X, Y, 1); err == nil {
+		t.Error("expected an error for a dropout rate of 1")
+	}
+}
+
+func TestDropoutLayer(t *testing.T) {
+	l, err := NewDropoutLayer(0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.setRand(rand.New(rand.NewSource(1)))
+	input := mat.NewDense(200, 50, nil)
+	input.Apply(func(i, j int, _ float64) float64 { return float64(i+j) + 1 }, input)
+
+	if output := l.Forward(input, false); !mat.Equal(output, input) {
+		t.Error("dropout changed its input at inference time")
+	}
+
+	output := l.Forward(input, true)
+	rows, cols := input.Dims()
+	dropped := 0
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			switch got := output.At(i, j); {
+			case got == 0:
+				dropped++
+			case math.Abs(got-input.At(i, j)/0.75) > 1e-12:
+				t.Fatalf("(%d, %d): %v, expected 0 or %v", i, j, got, input.At(i, j)/0.75)
+			}
+		}
+	}
+	if fraction := float64(dropped) / float64(rows*cols); math.Abs(fraction-0.25) > 0.02 {
+		t.Errorf("dropped %.3f of the inputs, expected about 0.25", fraction)
+	}
+}
+
+func TestBatchNormLayerRunningStatistics(t *testing.T) {
+	l := NewBatchNormLayer(2)
+	l.Gamma.Value.SetRow(0, []float64{2, 0.5})
+	l.Beta.Value.SetRow(0, []float64{1, -1})
+	l.RunningMean = []float64{3, -2}
+	l.RunningVar = []float64{4, 0.25}
+
+	// At inference time the running statistics replace the batch ones, so
+	// a single row is normalized too
+	input := mat.NewDense(1, 2, []float64{5, -1})
+	output := l.Forward(input, false)
+	for j, want := range []float64{
+		2*(5-3)/math.Sqrt(4+l.Epsilon) + 1,
+		0.5*(-1+2)/math.Sqrt(0.25+l.Epsilon) - 1,
+	} {
+		if got := output.At(0, j); math.Abs(got-want) > 1e-12 {
+			t.Errorf("feature %d: %v, expected %v", j, got, want)
+		}
+	}
+	if l.RunningMean[0] != 3 || l.RunningVar[0] != 4 {
+		t.Error("inference changed the running statistics")
+	}
+
+	// Training uses the batch statistics and folds them into the running
+	// averages with the unbiased variance
+	batch := mat.NewDense(2, 2, []float64{1, 0, 3, 0})
+	output = l.Forward(batch, true)
+	if got, want := output.At(0, 0), 2*(1-2)/math.Sqrt(1+l.Epsilon)+1; math.Abs(got-want) > 1e-12 {
+		t.Errorf("training output %v, expected %v", got, want)
+	}
+	if got, want := l.RunningMean[0], 0.9*3+0.1*2; math.Abs(got-want) > 1e-12 {
+		t.Errorf("running mean %v, expected %v", got, want)
+	}
+	if got, want := l.RunningVar[0], 0.9*4+0.1*2; math.Abs(got-want) > 1e-12 {
+		t.Errorf("running variance %v, expected %v", got, want)
+	}
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Loss measures how far the raw network outputs (the logits of the last
+// layer) are from the targets, one sample per row. Loss returns the mean
+// over the batch and Gradient its derivative with respect to the raw
+// outputs.
+type Loss interface {
+	Loss(output, target *mat.Dense) float64
+	Gradient(output, target *mat.Dense) *mat.Dense
+}
+
+// SoftmaxCrossEntropy applies softmax to the logits and measures the
+// categorical cross-entropy against one-hot targets. Combining both keeps
+// the gradient as simple and stable as softmax(z) - target.
+type SoftmaxCrossEntropy struct{}
+
+func (SoftmaxCrossEntropy) Loss(output, target *mat.Dense) float64 {
+	rows, _ := output.Dims()
+	loss := 0.0
+	for i := 0; i < rows; i++ {
+		logits := output.RawRowView(i)
+		norm := logSumExp(logits)
+		for j, t := range target.RawRowView(i) {
+			if t != 0 {
+				loss -= t * (logits[j] - norm)
+			}
+		}
+	}
+	return loss / float64(rows)
+}
+
+func (SoftmaxCrossEntropy) Gradient(output, target *mat.Dense) *mat.Dense {
+	rows, cols := output.Dims()
+	gradient := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		row := gradient.RawRowView(i)
+		copy(row, softmax(output.RawRowView(i)))
+		for j, t := range target.RawRowView(i) {
+			row[j] = (row[j] - t) / float64(rows)
+		}
+	}
+	return gradient
+}
+
+// SigmoidBinaryCrossEntropy applies an independent sigmoid to each logit
+// and sums the binary cross-entropies, for binary and multilabel targets
+type SigmoidBinaryCrossEntropy struct{}
+
+func (SigmoidBinaryCrossEntropy) Loss(output, target *mat.Dense) float64 {
+	rows, _ := output.Dims()
+	loss := 0.0
+	for i := 0; i < rows; i++ {
+		targets := target.RawRowView(i)
+		for j, z := range output.RawRowView(i) {
+			// max(z, 0) - z*t + log(1 + exp(-|z|)) never overflows
+			loss += math.Max(z, 0) - z*targets[j] + math.Log1p(math.Exp(-math.Abs(z)))
+		}
+	}
+	return loss / float64(rows)
+}
+
+func (SigmoidBinaryCrossEntropy) Gradient(output, target *mat.Dense) *mat.Dense {
+	rows, cols := output.Dims()
+	gradient := mat.NewDense(rows, cols, nil)
+	gradient.Apply(func(i, j int, z float64) float64 {
+		return (sigmoid(z) - target.At(i, j)) / float64(rows)
+	}, output)
+	return gradient
+}
+
+// softmax normalizes logits into probabilities that sum to one
+func softmax(logits []float64) []float64 {
+	norm := logSumExp(logits)
+	proba := make([]float64, len(logits))
+	for i, z := range logits {
+		proba[i] = math.Exp(z - norm)
+	}
+	return proba
+}
+
+// MeanSquaredError is the mean of the squared differences over every
+// output of every sample
+type MeanSquaredError struct{}
+
+func (MeanSquaredError) Loss(output, target *mat.Dense) float64 {
+	rows, cols := output.Dims()
+	loss := 0.0
+	for i := 0; i < rows; i++ {
+		targets := target.RawRowView(i)
+		for j, y := range output.RawRowView(i) {
+			d := y - targets[j]
+			loss += d * d
+		}
+	}
+	return loss / float64(rows*cols)
+}
+
+func (MeanSquaredError) Gradient(output, target *mat.Dense) *mat.Dense {
+	rows, cols := output.Dims()
+	gradient := mat.NewDense(rows, cols, nil)
+	gradient.Apply(func(i, j int, y float64) float64 {
+		return 2 * (y - target.At(i, j)) / float64(rows*cols)
+	}, output)
+	return gradient
+}
+
+// MeanAbsoluteError is the mean of the absolute differences, less
+// sensitive to outliers than MeanSquaredError
+type MeanAbsoluteError struct{}
+
+func (MeanAbsoluteError) Loss(output, target *mat.Dense) float64 {
+	rows, cols := output.Dims()
+	loss := 0.0
+	for i := 0; i < rows; i++ {
+		targets := target.RawRowView(i)
+		for j, y := range output.RawRowView(i) {
+			loss += math.Abs(y - targets[j])
+		}
+	}
+	return loss / float64(rows*cols)
+}
+
+func (MeanAbsoluteError) Gradient(output, target *mat.Dense) *mat.Dense {
+	rows, cols := output.Dims()
+	gradient := mat.NewDense(rows, cols, nil)
+	n := float64(rows * cols)
+	gradient.Apply(func(i, j int, y float64) float64 {
+		d := y - target.At(i, j)
+		switch {
+		case d > 0:
+			return 1 / n
+		case d < 0:
+			return -1 / n
+		}
+		return 0
+	}, output)
+	return gradient
+}
+
+// Huber is quadratic for differences up to Delta and linear beyond, so it
+// behaves like MeanSquaredError near the target and like MeanAbsoluteError
+// for outliers. A zero Delta defaults to 1.
+type Huber struct {
+	Delta float64
+}
+
+func (h Huber) delta() float64 {
+	if h.Delta <= 0 {
+		return 1
+	}
+	return h.Delta
+}
+
+func (h Huber) Loss(output, target *mat.Dense) float64 {
+	rows, cols := output.Dims()
+	delta := h.delta()
+	loss := 0.0
+	for i := 0; i < rows; i++ {
+		targets := target.RawRowView(i)
+		for j, y := range output.RawRowView(i) {
+			d := math.Abs(y - targets[j])
+			if d <= delta {
+				loss += 0.5 * d * d
+			} else {
+				loss += delta * (d - 0.5*delta)
+			}
+		}
+	}
+	return loss / float64(rows*cols)
+}
+
+func (h Huber) Gradient(output, target *mat.Dense) *mat.Dense {
+	rows, cols := output.Dims()
+	delta := h.delta()
+	gradient := mat.NewDense(rows, cols, nil)
+	gradient.Apply(func(i, j int, y float64) float64 {
+		d := math.Max(-delta, math.Min(delta, y-target.At(i, j)))
+		return d / float64(rows*cols)
+	}, output)
+	return gradient
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"context"
+	"math/rand"
+)
+
+// MLPRegressor is a multi-layer perceptron with a linear output layer,
+// predicting one or more real-valued targets per sample. It shares the
+// layers, optimizers and training loop of MLPClassifier.
+type MLPRegressor struct {
+	Network
+	Loss Loss // nil uses MeanSquaredError
+}
+
+// NewMLPRegressor constructor. hiddenLayerSizes holds the number of nodes
+// of each hidden layer, from input to output, and outputNodes the number
+// of targets. src seeds the network like in NewMLPClassifier
+func NewMLPRegressor(inputNodes int, hiddenLayerSizes []int, outputNodes int, learningRate float64, src rand.Source) *MLPRegressor {
+	return &MLPRegressor{Network: newNetwork(inputNodes, hiddenLayerSizes, outputNodes, learningRate, Sigmoid{}, src)}
+}
+
+// loss returns the configured loss or MeanSquaredError
+func (mlp *MLPRegressor) loss() Loss {
+	if mlp.Loss == nil {
+		return MeanSquaredError{}
+	}
+	return mlp.Loss
+}
+
+// Fit trains the network for the given number of epochs using mini-batch
+// gradient descent and returns the per-epoch History, without accuracy
+func (mlp *MLPRegressor) Fit(X [][]float64, Y [][]float64, epochs int) (*History, error) {
+	return mlp.FitContext(context.Background(), X, Y, epochs)
+}
+
+// FitContext is Fit with cancellation, see MLPClassifier.FitContext
+func (mlp *MLPRegressor) FitContext(ctx context.Context, X [][]float64, Y [][]float64, epochs int) (*History, error) {
+	return mlp.fit(ctx, X, Y, epochs, mlp.loss(), nil)
+}
+
+// Predict returns the predicted targets of every sample
+func (mlp *MLPRegressor) Predict(X [][]float64) ([][]float64, error) {
+	if err := mlp.checkInputs(X); err != nil {
+		return nil, err
+	}
+	return denseToRows(mlp.forward(rowsToDense(X), false)), nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// linearData returns samples of two features with the target
+// 2*x0 - 3*x1 + 1 and, as a second target, its negation
+func linearData(n int, seed int64) ([][]float64, [][]float64) {
+	rng := rand.New(rand.NewSource(seed))
+	X := make([][]float64, n)
+	Y := make([][]float64, n)
+	for i := range X {
+		X[i] = []float64{rng.Float64()*2 - 1, rng.Float64()*2 - 1}
+		y := 2*X[i][0] - 3*X[i][1] + 1
+		Y[i] = []float64{y, -y}
+	}
+	return X, Y
+}
+
+func TestMLPRegressorFitsLinearTarget(t *testing.T) {
+	X, Y := linearData(64, 1)
+	for i := range Y {
+		Y[i] = Y[i][:1]
+	}
+	// Without hidden layers the identity output is a linear regression
+	mlp := NewMLPRegressor(2, nil, 1, 0.1, rand.NewSource(2))
+	if _, err := mlp.Fit(X, Y, 200); err != nil {
+		t.Fatal(err)
+	}
+	predictions, err := mlp.Predict([][]float64{{0, 0}, {1, 0}, {0.5, -0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{1, 3, 3.5} {
+		if math.Abs(predictions[i][0]-want) > 0.01 {
+			t.Errorf("sample %d: predicted %v, expected %v", i, predictions[i][0], want)
+		}
+	}
+}
+
+func TestMLPRegressorMultiOutputShape(t *testing.T) {
+	X, Y := linearData(10, 3)
+	mlp := NewMLPRegressor(2, []int{4}, 2, 0.05, rand.NewSource(4))
+	if _, err := mlp.Fit(X, Y, 1); err != nil {
+		t.Fatal(err)
+	}
+	predictions, err := mlp.Predict(X[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(predictions) != 3 {
+		t.Fatalf("got %d rows, expected 3", len(predictions))
+	}
+	for i, row := range predictions {
+		if len(row) != 2 {
+			t.Errorf("row %d has %d outputs, expected 2", i, len(row))
+		}
+	}
+}
+
+func TestMLPRegressorRobustLosses(t *testing.T) {
+	X, Y := linearData(64, 5)
+	for _, loss := range []Loss{MeanAbsoluteError{}, Huber{Delta: 0.5}} {
+		mlp := NewMLPRegressor(2, []int{8}, 2, 0.05, rand.NewSource(6))
+		mlp.Loss = loss
+		mlp.Optimizer = NewAdam(0.02)
+		history, err := mlp.Fit(X, Y, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, last := history.Loss[0], history.Loss[len(history.Loss)-1]
+		if last > first/2 {
+			t.Errorf("%T: loss went from %v to %v, expected it to halve", loss, first, last)
+		}
+	}
+}
+
+func TestMLPRegressorRejectsWrongWidth(t *testing.T) {
+	mlp := NewMLPRegressor(2, []int{3}, 1, 0.1, nil)
+	if _, err := mlp.Predict([][]float64{{1, 2}, {1, 2, 3}}); err == nil {
+		t.Error("Predict: expected an error for a 3-feature row")
+	}
+	if _, err := mlp.Predict(nil); err == nil {
+		t.Error("Predict: expected an error for no rows")
+	}
+	if _, err := mlp.Fit([][]float64{{1}}, [][]float64{{1}}, 1); err == nil {
+		t.Error("Fit: expected an error for a 1-feature row")
+	}
+	if _, err := mlp.Fit([][]float64{{1, 2}}, [][]float64{{1, 2}}, 1); err == nil {
+		t.Error("Fit: expected an error for 2 targets on 1 output")
+	}
+}
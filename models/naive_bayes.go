@@ -2,122 +2,700 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"math"
+	"reflect"
 )
 
-// GaussianNB representa un clasificador Naive Bayes Gaussiano
-type GaussianNB struct {
-	classProbabilities map[interface{}]float64          // Probabilidad de cada clase
-	featureStats       map[interface{}][]FeatureStat    // Media y desviación estándar de cada característica por clase
-	classes            []interface{}                    // Lista de clases
+// naiveBayes contiene la lógica común a todos los clasificadores Naive
+// Bayes: registro de clases, conteos por clase, probabilidades a priori y
+// salidas de probabilidad
+type naiveBayes[L comparable] struct {
+	classes     []L       // Lista de clases en orden de aparición
+	classIndex  map[L]int // Índice de cada clase en classes
+	classCounts []float64 // Cantidad de muestras vistas por clase
+
+	// Priors fija P(c) en el orden de Classes() en lugar de estimarla con
+	// las frecuencias de entrenamiento; nil usa las frecuencias
+	Priors []float64
 }
 
-// FeatureStat almacena la media y desviación estándar de una característica
-type FeatureStat struct {
-	Mean   float64
-	StdDev float64
+// Valores por defecto de la re-estimación de priors con EM
+const (
+	defaultPriorsMaxIter = 100
+	defaultPriorsTol     = 1e-6
+)
+
+// reset elimina las clases y conteos acumulados
+func (nb *naiveBayes[L]) reset() {
+	nb.classes = nil
+	nb.classIndex = nil
+	nb.classCounts = nil
+}
+
+// addClass registra una clase si es nueva y devuelve su índice
+func (nb *naiveBayes[L]) addClass(label L) int {
+	if nb.classIndex == nil {
+		nb.classIndex = make(map[L]int)
+	}
+	if idx, ok := nb.classIndex[label]; ok {
+		return idx
+	}
+	nb.classIndex[label] = len(nb.classes)
+	nb.classes = append(nb.classes, label)
+	nb.classCounts = append(nb.classCounts, 0)
+	return len(nb.classes) - 1
+}
+
+// countLabels registra las clases declaradas en classes y las que aparecen
+// en y, suma las muestras a los conteos y devuelve el índice de clase de
+// cada muestra
+func (nb *naiveBayes[L]) countLabels(y, classes []L) []int {
+	for _, label := range classes {
+		nb.addClass(label)
+	}
+	indices := make([]int, len(y))
+	for i, label := range y {
+		indices[i] = nb.addClass(label)
+		nb.classCounts[indices[i]]++
+	}
+	return indices
+}
+
+// checkPriors verifica que Priors sea compatible con las clases que
+// quedarían registradas tras ver y y classes
+func (nb *naiveBayes[L]) checkPriors(y, classes []L) error {
+	if nb.Priors == nil {
+		return nil
+	}
+	nClasses := len(nb.classes)
+	seen := make(map[L]bool)
+	for _, labels := range [][]L{classes, y} {
+		for _, label := range labels {
+			if _, ok := nb.classIndex[label]; !ok && !seen[label] {
+				seen[label] = true
+				nClasses++
+			}
+		}
+	}
+	return validatePriors(nb.Priors, nClasses)
+}
+
+// validatePriors verifica que priors tenga una probabilidad no negativa
+// por clase y que sumen 1
+func validatePriors(priors []float64, nClasses int) error {
+	if len(priors) != nClasses {
+		return fmt.Errorf("se esperaban %d priors, se recibieron %d", nClasses, len(priors))
+	}
+	sum := 0.0
+	for _, p := range priors {
+		if p < 0 {
+			return errors.New("las priors no pueden ser negativas")
+		}
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		return fmt.Errorf("las priors deben sumar 1, suman %g", sum)
+	}
+	return nil
+}
+
+// SetPriors cambia P(c) usada en la predicción sin volver a entrenar.
+// priors sigue el orden de Classes(); nil vuelve a las frecuencias de
+// entrenamiento
+func (nb *naiveBayes[L]) SetPriors(priors []float64) error {
+	if priors == nil {
+		nb.Priors = nil
+		return nil
+	}
+	if err := validatePriors(priors, len(nb.classes)); err != nil {
+		return err
+	}
+	nb.Priors = append([]float64(nil), priors...)
+	return nil
+}
+
+// ClassPriors devuelve P(c) usada en la predicción, en el orden de
+// Classes()
+func (nb *naiveBayes[L]) ClassPriors() ([]float64, error) {
+	if err := nb.checkFitted(); err != nil {
+		return nil, err
+	}
+	priors := nb.classLogPriors()
+	for k := range priors {
+		priors[k] = math.Exp(priors[k])
+	}
+	return priors, nil
+}
+
+// checkFitted verifica que el modelo esté entrenado y que Priors, que
+// puede haberse asignado directamente, corresponda a las clases actuales
+func (nb *naiveBayes[L]) checkFitted() error {
+	if len(nb.classes) == 0 {
+		return errors.New("el modelo no ha sido entrenado")
+	}
+	if nb.Priors != nil {
+		if err := validatePriors(nb.Priors, len(nb.classes)); err != nil {
+			return fmt.Errorf("Priors inválidas: %w", err)
+		}
+	}
+	return nil
+}
+
+// totalCount devuelve la cantidad de muestras vistas
+func (nb *naiveBayes[L]) totalCount() float64 {
+	total := 0.0
+	for _, c := range nb.classCounts {
+		total += c
+	}
+	return total
+}
+
+// classLogPriors devuelve log P(c), tomada de Priors o estimada a partir
+// de las frecuencias. Priors debe haber pasado por checkFitted
+func (nb *naiveBayes[L]) classLogPriors() []float64 {
+	priors := make([]float64, len(nb.classes))
+	if nb.Priors != nil {
+		for k, p := range nb.Priors {
+			priors[k] = math.Log(p)
+		}
+		return priors
+	}
+	total := nb.totalCount()
+	for k, c := range nb.classCounts {
+		priors[k] = math.Log(c / total)
+	}
+	return priors
+}
+
+// adaptPriors re-estima P(c) sobre datos sin etiquetar del dominio de
+// destino con el algoritmo EM de Saerens et al. (2002) y la fija como
+// Priors. Las posteriores del modelo se reponderan con el cociente entre
+// las priors nuevas y las usadas en el entrenamiento hasta que éstas
+// convergen
+func adaptPriors[L comparable, T any](nb *naiveBayes[L], X [][]T, nFeatures int, jll func([]T) []float64, maxIter int, tol float64) ([]float64, error) {
+	if err := nb.checkFitted(); err != nil {
+		return nil, err
+	}
+	if len(X) == 0 {
+		return nil, errors.New("el parámetro X está vacío")
+	}
+	if maxIter <= 0 {
+		maxIter = defaultPriorsMaxIter
+	}
+	if tol <= 0 {
+		tol = defaultPriorsTol
+	}
+
+	basePriors := nb.classLogPriors()
+	logProba, err := predictLogProba(nb, X, nFeatures, jll)
+	if err != nil {
+		return nil, err
+	}
+
+	priors := make([]float64, len(basePriors))
+	for k := range priors {
+		priors[k] = math.Exp(basePriors[k])
+	}
+
+	scores := make([]float64, len(priors))
+	for iter := 0; iter < maxIter; iter++ {
+		// Paso E: posteriores ajustadas a las priors actuales
+		next := make([]float64, len(priors))
+		for _, row := range logProba {
+			for k := range row {
+				if math.IsInf(basePriors[k], -1) {
+					scores[k] = math.Inf(-1) // Clase sin probabilidad a priori
+					continue
+				}
+				scores[k] = row[k] + math.Log(priors[k]) - basePriors[k]
+			}
+			norm := logSumExp(scores)
+			for k := range scores {
+				next[k] += math.Exp(scores[k] - norm)
+			}
+		}
+
+		// Paso M: nuevas priors como media de las posteriores
+		change := 0.0
+		for k := range next {
+			next[k] /= float64(len(X))
+			change = math.Max(change, math.Abs(next[k]-priors[k]))
+		}
+		priors = next
+		if change < tol {
+			break
+		}
+	}
+
+	nb.Priors = priors
+	return append([]float64(nil), priors...), nil
+}
+
+// Classes devuelve las clases en el orden de las columnas de PredictProba
+func (nb *naiveBayes[L]) Classes() []L {
+	return append([]L(nil), nb.classes...)
+}
+
+// predictLabels elige para cada muestra la clase de mayor verosimilitud
+// conjunta
+func predictLabels[L comparable, T any](nb *naiveBayes[L], X [][]T, nFeatures int, jll func([]T) []float64) ([]L, error) {
+	if err := checkNBSamples(nb, X, nFeatures); err != nil {
+		return nil, err
+	}
+	yPred := make([]L, len(X))
+	for i, sample := range X {
+		yPred[i] = nb.classes[argmax(jll(sample))]
+	}
+	return yPred, nil
+}
+
+// predictLogProba normaliza la verosimilitud conjunta de cada muestra con
+// log-sum-exp para obtener log P(c|x)
+func predictLogProba[L comparable, T any](nb *naiveBayes[L], X [][]T, nFeatures int, jll func([]T) []float64) ([][]float64, error) {
+	if err := checkNBSamples(nb, X, nFeatures); err != nil {
+		return nil, err
+	}
+	logProba := make([][]float64, len(X))
+	for i, sample := range X {
+		scores := jll(sample)
+		norm := logSumExp(scores)
+		for k := range scores {
+			scores[k] -= norm
+		}
+		logProba[i] = scores
+	}
+	return logProba, nil
+}
+
+// predictProba devuelve P(c|x) a partir de predictLogProba
+func predictProba[L comparable, T any](nb *naiveBayes[L], X [][]T, nFeatures int, jll func([]T) []float64) ([][]float64, error) {
+	proba, err := predictLogProba(nb, X, nFeatures, jll)
+	if err != nil {
+		return nil, err
+	}
+	for i := range proba {
+		for k := range proba[i] {
+			proba[i][k] = math.Exp(proba[i][k])
+		}
+	}
+	return proba, nil
+}
+
+// logSumExp calcula log(sum(exp(v))) de forma numéricamente estable
+func logSumExp(v []float64) float64 {
+	maxV := math.Inf(-1)
+	for _, x := range v {
+		maxV = math.Max(maxV, x)
+	}
+	if math.IsInf(maxV, 0) {
+		return maxV
+	}
+	sum := 0.0
+	for _, x := range v {
+		sum += math.Exp(x - maxV)
+	}
+	return maxV + math.Log(sum)
+}
+
+// argmax devuelve el índice del mayor valor; el primero en caso de empate
+func argmax(v []float64) int {
+	best := 0
+	for i := range v {
+		if v[i] > v[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// checkNBSamples verifica que el modelo esté entrenado y que todas las
+// muestras a predecir tengan las nFeatures características del ajuste
+func checkNBSamples[L comparable, T any](nb *naiveBayes[L], X [][]T, nFeatures int) error {
+	if err := nb.checkFitted(); err != nil {
+		return err
+	}
+	for i, row := range X {
+		if len(row) != nFeatures {
+			return fmt.Errorf("la fila %d de X tiene %d características, se esperaban %d", i, len(row), nFeatures)
+		}
+	}
+	return nil
 }
 
-// checkDataLength verifica que los datos tengan el mismo tamaño
-func (gnb *GaussianNB) checkDataLength(X [][]float64, y []interface{}) error {
+// checkNBData verifica que X e y tengan el mismo tamaño, no estén vacíos y
+// que todas las filas tengan nFeatures columnas (o las de la primera fila
+// si nFeatures es 0)
+func checkNBData[T any, L comparable](X [][]T, y []L, nFeatures int) error {
 	if len(X) != len(y) {
 		return errors.New("los parámetros X e y no tienen la misma longitud")
 	}
 	if len(X) == 0 || len(y) == 0 {
 		return errors.New("los parámetros X o y están vacíos")
 	}
+	if nFeatures == 0 {
+		nFeatures = len(X[0])
+	}
+	for _, row := range X {
+		if len(row) != nFeatures {
+			return fmt.Errorf("X tiene filas con %d características, se esperaban %d", len(row), nFeatures)
+		}
+	}
 	return nil
 }
 
-// Fit ajusta el modelo Naive Bayes Gaussiano a los datos
-func (gnb *GaussianNB) Fit(X [][]float64, y []interface{}) error {
-	if err := gnb.checkDataLength(X, y); err != nil {
-		return err
-	}
+// GaussianNB representa un clasificador Naive Bayes Gaussiano con
+// etiquetas de tipo L
+type GaussianNB[L comparable] struct {
+	naiveBayes[L]
+
+	// VarSmoothing es la fracción de la mayor varianza entre
+	// características que se suma a todas las varianzas para estabilizar
+	// el cálculo; 0 usa el valor por defecto 1e-9. No se puede desactivar:
+	// una característica constante en una clase tendría varianza nula y
+	// densidad infinita, así que para suavizar lo mínimo se usa un valor
+	// positivo pequeño
+	VarSmoothing float64
+	epsilon      float64
+
+	featureStats [][]FeatureStat // Media y desviación estándar de cada característica por clase
+	classMoments []*moments      // Conteo, media y M2 acumulados por clase
+	totalMoments *moments        // Conteo, media y M2 de todas las muestras
+}
+
+// defaultVarSmoothing es el valor de VarSmoothing cuando no se especifica
+const defaultVarSmoothing = 1e-9
 
-	classCounts := make(map[interface{}]float64)
-	featureSums := make(map[interface{}][]float64)
-	featureSquaredSums := make(map[interface{}][]float64)
+// FeatureStat almacena la media y desviación estándar de una característica
+type FeatureStat struct {
+	Mean   float64
+	StdDev float64
+}
 
-	// Inicializamos las estructuras y sumamos
-	for i := 0; i < len(y); i++ {
-		label := y[i]
-		classCounts[label]++
-		if _, ok := featureSums[label]; !ok {
-			featureSums[label] = make([]float64, len(X[0]))
-			featureSquaredSums[label] = make([]float64, len(X[0]))
+// moments acumula conteo, media y suma de cuadrados centrados (M2) por
+// característica, combinables con el algoritmo paralelo de Chan
+type moments struct {
+	count float64
+	mean  []float64
+	m2    []float64
+}
+
+// newMoments calcula los momentos de un lote en dos pasadas
+func newMoments(X [][]float64) *moments {
+	nFeatures := len(X[0])
+	m := &moments{
+		count: float64(len(X)),
+		mean:  make([]float64, nFeatures),
+		m2:    make([]float64, nFeatures),
+	}
+	for _, row := range X {
+		for j, v := range row {
+			m.mean[j] += v
 		}
+	}
+	for j := range m.mean {
+		m.mean[j] /= m.count
+	}
+	for _, row := range X {
+		for j, v := range row {
+			d := v - m.mean[j]
+			m.m2[j] += d * d
+		}
+	}
+	return m
+}
+
+// combine agrega los momentos de other usando el algoritmo de Chan
+func (m *moments) combine(other *moments) {
+	if other.count == 0 {
+		return
+	}
+	if m.count == 0 {
+		m.count = other.count
+		m.mean = append([]float64(nil), other.mean...)
+		m.m2 = append([]float64(nil), other.m2...)
+		return
+	}
+	n := m.count + other.count
+	for j := range m.mean {
+		delta := other.mean[j] - m.mean[j]
+		m.mean[j] += delta * other.count / n
+		m.m2[j] += other.m2[j] + delta*delta*m.count*other.count/n
+	}
+	m.count = n
+}
 
-		for j := 0; j < len(X[i]); j++ {
-			featureSums[label][j] += X[i][j]
-			featureSquaredSums[label][j] += X[i][j] * X[i][j]
+// variance devuelve la varianza poblacional de la característica j
+func (m *moments) variance(j int) float64 {
+	if m.count == 0 {
+		return 0
+	}
+	return m.m2[j] / m.count
+}
+
+// nFeatures devuelve la cantidad de características vistas; 0 si no hay
+// ajuste previo
+func (gnb *GaussianNB[L]) nFeatures() int {
+	if gnb.totalMoments == nil {
+		return 0
+	}
+	return len(gnb.totalMoments.mean)
+}
+
+// Fit ajusta el modelo Naive Bayes Gaussiano a los datos, descartando
+// cualquier ajuste previo
+func (gnb *GaussianNB[L]) Fit(X [][]float64, y []L) error {
+	if err := checkNBData(X, y, 0); err != nil {
+		return err
+	}
+	gnb.reset()
+	gnb.classMoments = nil
+	gnb.totalMoments = nil
+	return gnb.PartialFit(X, y, nil)
+}
+
+// PartialFit actualiza el modelo con un nuevo lote de datos sin
+// recalcular desde cero. classes es opcional y permite declarar de
+// antemano todas las clases y su orden; las etiquetas nuevas que
+// aparezcan en y se agregan al final
+func (gnb *GaussianNB[L]) PartialFit(X [][]float64, y []L, classes []L) error {
+	if err := checkNBData(X, y, gnb.nFeatures()); err != nil {
+		return err
+	}
+	if err := gnb.checkPriors(y, classes); err != nil {
+		return err
+	}
+	if gnb.totalMoments == nil {
+		gnb.totalMoments = &moments{}
+	}
+
+	// Agrupamos las filas por clase
+	indices := gnb.countLabels(y, classes)
+	gnb.growMoments()
+	rowsByClass := make([][][]float64, len(gnb.classes))
+	for i, k := range indices {
+		rowsByClass[k] = append(rowsByClass[k], X[i])
+	}
+	for k, rows := range rowsByClass {
+		if len(rows) > 0 {
+			gnb.classMoments[k].combine(newMoments(rows))
 		}
 	}
+	gnb.totalMoments.combine(newMoments(X))
 
-	totalCount := float64(len(y))
+	gnb.updateStats()
+	return nil
+}
 
-	// Guardamos las clases
-	gnb.classes = make([]interface{}, 0, len(classCounts))
-	for label := range classCounts {
-		gnb.classes = append(gnb.classes, label)
+// Merge combina en gnb un modelo entrenado con otro fragmento de datos.
+// El resultado equivale a haber entrenado con la unión de ambos conjuntos.
+// Antes de combinar nada verifica que ambos tengan la misma cantidad de
+// características y que Priors siga siendo válida con las clases de other
+func (gnb *GaussianNB[L]) Merge(other *GaussianNB[L]) error {
+	if other.totalMoments == nil {
+		return nil
+	}
+	if gnb.totalMoments != nil && gnb.nFeatures() != other.nFeatures() {
+		return fmt.Errorf("los modelos tienen %d y %d características", gnb.nFeatures(), other.nFeatures())
+	}
+	if err := gnb.checkPriors(nil, other.classes); err != nil {
+		return err
+	}
+	if gnb.totalMoments == nil {
+		gnb.totalMoments = &moments{}
 	}
 
-	// Calculamos la probabilidad de cada clase
-	gnb.classProbabilities = make(map[interface{}]float64)
-	for _, label := range gnb.classes {
-		gnb.classProbabilities[label] = classCounts[label] / totalCount
+	for k, label := range other.classes {
+		idx := gnb.addClass(label)
+		gnb.classCounts[idx] += other.classCounts[k]
+		gnb.growMoments()
+		gnb.classMoments[idx].combine(other.classMoments[k])
 	}
+	gnb.totalMoments.combine(other.totalMoments)
+
+	gnb.updateStats()
+	return nil
+}
 
-	// Calculamos media y desviación estándar
-	gnb.featureStats = make(map[interface{}][]FeatureStat)
-	for _, label := range gnb.classes {
-		nFeatures := len(X[0])
-		gnb.featureStats[label] = make([]FeatureStat, nFeatures)
-		for j := 0; j < nFeatures; j++ {
-			mean := featureSums[label][j] / classCounts[label]
-			variance := (featureSquaredSums[label][j] / classCounts[label]) - mean*mean
-			stdDev := math.Sqrt(variance)
-			if stdDev == 0 {
-				stdDev = 1e-10 // Evita división por cero
+// growMoments agrega momentos vacíos para las clases recién registradas
+func (gnb *GaussianNB[L]) growMoments() {
+	for len(gnb.classMoments) < len(gnb.classes) {
+		gnb.classMoments = append(gnb.classMoments, &moments{})
+	}
+}
+
+// updateStats recalcula el suavizado y las estadísticas por característica
+// a partir de los momentos acumulados
+func (gnb *GaussianNB[L]) updateStats() {
+	gnb.epsilon = gnb.varianceEpsilon()
+	nFeatures := gnb.nFeatures()
+
+	gnb.featureStats = make([][]FeatureStat, len(gnb.classes))
+	for k, m := range gnb.classMoments {
+		stats := make([]FeatureStat, nFeatures)
+		for j := range stats {
+			mean := 0.0
+			if m.count > 0 {
+				mean = m.mean[j]
 			}
-			gnb.featureStats[label][j] = FeatureStat{
+			stats[j] = FeatureStat{
 				Mean:   mean,
-				StdDev: stdDev,
+				StdDev: math.Sqrt(m.variance(j) + gnb.epsilon),
 			}
 		}
+		gnb.featureStats[k] = stats
 	}
+}
 
-	return nil
+// varianceEpsilon calcula el suavizado de varianza a partir de la mayor
+// varianza entre las características de todas las muestras vistas
+func (gnb *GaussianNB[L]) varianceEpsilon() float64 {
+	smoothing := gnb.VarSmoothing
+	if smoothing == 0 {
+		smoothing = defaultVarSmoothing
+	}
+
+	maxVariance := 0.0
+	for j := range gnb.totalMoments.mean {
+		maxVariance = math.Max(maxVariance, gnb.totalMoments.variance(j))
+	}
+
+	if maxVariance == 0 {
+		// Todas las características son constantes: usamos el suavizado absoluto
+		return smoothing
+	}
+	return smoothing * maxVariance
+}
+
+// logGaussian calcula el logaritmo de la densidad normal sin pasar por el
+// espacio lineal, evitando el underflow en puntos alejados
+func (gnb *GaussianNB[L]) logGaussian(x, mean, stdDev float64) float64 {
+	z := (x - mean) / stdDev
+	return -0.5*z*z - math.Log(stdDev) - 0.5*math.Log(2*math.Pi)
 }
 
-// gaussian calcula la probabilidad de un valor bajo distribución normal
-func (gnb *GaussianNB) gaussian(x, mean, stdDev float64) float64 {
-	exponent := math.Exp(-0.5 * math.Pow((x-mean)/stdDev, 2))
-	return (1 / (stdDev * math.Sqrt(2*math.Pi))) * exponent
+// jointLogLikelihood calcula log P(c) + log P(x|c) para cada clase
+func (gnb *GaussianNB[L]) jointLogLikelihood(sample []float64) []float64 {
+	scores := gnb.classLogPriors()
+	for k := range scores {
+		for j, fs := range gnb.featureStats[k] {
+			scores[k] += gnb.logGaussian(sample[j], fs.Mean, fs.StdDev)
+		}
+	}
+	return scores
+}
+
+// AdaptPriors re-estima P(c) con EM sobre datos sin etiquetar del dominio
+// de destino para corregir un cambio en la distribución de clases, la fija
+// como Priors y la devuelve. maxIter y tol <= 0 usan los valores por
+// defecto
+func (gnb *GaussianNB[L]) AdaptPriors(X [][]float64, maxIter int, tol float64) ([]float64, error) {
+	return adaptPriors(&gnb.naiveBayes, X, gnb.nFeatures(), gnb.jointLogLikelihood, maxIter, tol)
 }
 
 // Predict realiza predicciones sobre nuevos datos
-func (gnb *GaussianNB) Predict(X [][]float64) []interface{} {
-	yPred := make([]interface{}, len(X))
+func (gnb *GaussianNB[L]) Predict(X [][]float64) ([]L, error) {
+	return predictLabels(&gnb.naiveBayes, X, gnb.nFeatures(), gnb.jointLogLikelihood)
+}
 
-	for i, sample := range X {
-		bestLabel := gnb.classes[0]
-		bestScore := math.Inf(-1)
+// PredictLogProba devuelve log P(c|x) para cada muestra y clase,
+// normalizado con log-sum-exp
+func (gnb *GaussianNB[L]) PredictLogProba(X [][]float64) ([][]float64, error) {
+	return predictLogProba(&gnb.naiveBayes, X, gnb.nFeatures(), gnb.jointLogLikelihood)
+}
 
-		for _, label := range gnb.classes {
-			score := math.Log(gnb.classProbabilities[label])
-			for j, fs := range gnb.featureStats[label] {
-				score += math.Log(gnb.gaussian(sample[j], fs.Mean, fs.StdDev))
-			}
+// PredictProba devuelve P(c|x) para cada muestra y clase
+func (gnb *GaussianNB[L]) PredictProba(X [][]float64) ([][]float64, error) {
+	return predictProba(&gnb.naiveBayes, X, gnb.nFeatures(), gnb.jointLogLikelihood)
+}
 
-			if score > bestScore {
-				bestScore = score
-				bestLabel = label
-			}
-		}
+// GaussianNBAdapter mantiene la API basada en interface{} sobre
+// GaussianNB[interface{}], validando las etiquetas antes de usarlas como
+// claves de mapa. Los métodos que reciben etiquetas (Fit, PartialFit y
+// Merge) se redefinen, y los de predicción conservan la firma original
+// sin error; el resto, como SetPriors o AdaptPriors, sólo usa las clases
+// ya validadas y se hereda sin cambios
+type GaussianNBAdapter struct {
+	GaussianNB[interface{}]
+}
+
+// Fit ajusta el modelo; devuelve error si alguna etiqueta no es comparable
+func (a *GaussianNBAdapter) Fit(X [][]float64, y []interface{}) error {
+	if err := checkHashableLabels(y); err != nil {
+		return err
+	}
+	return a.GaussianNB.Fit(X, y)
+}
 
-		yPred[i] = bestLabel
+// PartialFit actualiza el modelo; devuelve error si alguna etiqueta no es
+// comparable
+func (a *GaussianNBAdapter) PartialFit(X [][]float64, y []interface{}, classes []interface{}) error {
+	if err := checkHashableLabels(y); err != nil {
+		return err
 	}
+	if err := checkHashableLabels(classes); err != nil {
+		return err
+	}
+	return a.GaussianNB.PartialFit(X, y, classes)
+}
 
+// Merge combina las estadísticas de otro modelo entrenado por separado;
+// devuelve error si alguna de sus clases no es comparable
+func (a *GaussianNBAdapter) Merge(other *GaussianNBAdapter) error {
+	if err := checkHashableLabels(other.classes); err != nil {
+		return err
+	}
+	return a.GaussianNB.Merge(&other.GaussianNB)
+}
+
+// Predict realiza predicciones sobre nuevos datos. Como la versión
+// original, entra en pánico si el modelo no está entrenado o X no es
+// válido; GaussianNB.Predict devuelve el error en su lugar
+func (a *GaussianNBAdapter) Predict(X [][]float64) []interface{} {
+	yPred, err := a.GaussianNB.Predict(X)
+	if err != nil {
+		panic(err)
+	}
 	return yPred
 }
+
+// PredictLogProba devuelve log P(c|x) para cada muestra y clase; entra en
+// pánico en los mismos casos que Predict
+func (a *GaussianNBAdapter) PredictLogProba(X [][]float64) [][]float64 {
+	logProba, err := a.GaussianNB.PredictLogProba(X)
+	if err != nil {
+		panic(err)
+	}
+	return logProba
+}
+
+// PredictProba devuelve P(c|x) para cada muestra y clase; entra en pánico
+// en los mismos casos que Predict
+func (a *GaussianNBAdapter) PredictProba(X [][]float64) [][]float64 {
+	proba, err := a.GaussianNB.PredictProba(X)
+	if err != nil {
+		panic(err)
+	}
+	return proba
+}
+
+// ClassPriors devuelve P(c) usada en la predicción, en el orden de
+// Classes(); entra en pánico en los mismos casos que Predict
+func (a *GaussianNBAdapter) ClassPriors() []float64 {
+	priors, err := a.GaussianNB.ClassPriors()
+	if err != nil {
+		panic(err)
+	}
+	return priors
+}
+
+// checkHashableLabels verifica que todas las etiquetas puedan usarse como
+// claves de mapa sin provocar un panic
+func checkHashableLabels(y []interface{}) error {
+	for i, label := range y {
+		if label != nil && !reflect.ValueOf(label).Comparable() {
+			return fmt.Errorf("la etiqueta en la posición %d (%T) no es comparable", i, label)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,536 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// discreteNB acumula por clase la suma de cada característica; es la base
+// de MultinomialNB, ComplementNB y BernoulliNB
+type discreteNB[L comparable] struct {
+	naiveBayes[L]
+	featureCounts [][]float64 // Suma de cada característica por clase
+	nFeatures     int         // Cantidad de características; 0 si no hay ajuste previo
+}
+
+// reset elimina clases y conteos acumulados
+func (d *discreteNB[L]) reset() {
+	d.naiveBayes.reset()
+	d.featureCounts = nil
+	d.nFeatures = 0
+}
+
+// accumulate suma las filas de X a los conteos de su clase
+func (d *discreteNB[L]) accumulate(X [][]float64, y, classes []L) {
+	d.nFeatures = len(X[0])
+	indices := d.countLabels(y, classes)
+	for len(d.featureCounts) < len(d.classes) {
+		d.featureCounts = append(d.featureCounts, make([]float64, d.nFeatures))
+	}
+	for i, k := range indices {
+		for j, v := range X[i] {
+			d.featureCounts[k][j] += v
+		}
+	}
+}
+
+// minAlpha es el suavizado que se usa cuando se pide ninguno, para que
+// las características no vistas en una clase no den log(0)
+const minAlpha = 1e-10
+
+// alphaOrDefault devuelve el suavizado efectivo: el de Laplace (1) si
+// alpha es 0, el val[REDACTED] si es negativo (sin suavizado) y alpha en
+// otro caso
+func alphaOrDefault(alpha float64) float64 {
+	switch {
+	case alpha == 0:
+		return 1
+	case alpha < 0:
+		return minAlpha
+	}
+	return alpha
+}
+
+// checkNonNegative verifica que X no tenga valores negativos
+func checkNonNegative(X [][]float64) error {
+	for i, row := range X {
+		for j, v := range row {
+			if v < 0 {
+				return fmt.Errorf("X[%d][%d] es negativo; se esperaban conteos o frecuencias", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// checkUnitInterval verifica que todos los valores de X estén en [0, 1]
+func checkUnitInterval(X [][]float64) error {
+	for i, row := range X {
+		for j, v := range row {
+			if v < 0 || v > 1 {
+				return fmt.Errorf("X[%d][%d] = %v está fuera de [0, 1]", i, j, v)
+			}
+		}
+	}
+	return nil
+}
+
+// dotLogProb calcula el producto escalar entre una muestra y una fila de
+// log-probabilidades por característica
+func dotLogProb(sample, logProb []float64) float64 {
+	sum := 0.0
+	for j, v := range sample {
+		if v != 0 {
+			sum += v * logProb[j]
+		}
+	}
+	return sum
+}
+
+// MultinomialNB es un clasificador Naive Bayes para conteos, por ejemplo
+// frecuencias de palabras en texto
+type MultinomialNB[L comparable] struct {
+	discreteNB[L]
+
+	// Alpha es el suavizado aditivo (Laplace si es 1, Lidstone si es
+	// menor); 0 usa el valor por defecto 1 y un valor negativo desactiva
+	// el suavizado
+	Alpha float64
+
+	featureLogProb [][]float64 // log P(x_j|c)
+}
+
+// Fit ajusta el modelo a los conteos de X, descartando ajustes previos
+func (m *MultinomialNB[L]) Fit(X [][]float64, y []L) error {
+	if err := checkNBData(X, y, 0); err != nil {
+		return err
+	}
+	m.reset()
+	return m.PartialFit(X, y, nil)
+}
+
+// PartialFit actualiza los conteos con un nuevo lote de datos
+func (m *MultinomialNB[L]) PartialFit(X [][]float64, y []L, classes []L) error {
+	if err := checkNBData(X, y, m.nFeatures); err != nil {
+		return err
+	}
+	if err := m.checkPriors(y, classes); err != nil {
+		return err
+	}
+	if err := checkNonNegative(X); err != nil {
+		return err
+	}
+	m.accumulate(X, y, classes)
+	m.updateFeatureLogProb()
+	return nil
+}
+
+// updateFeatureLogProb recalcula log P(x_j|c) con suavizado aditivo
+func (m *MultinomialNB[L]) updateFeatureLogProb() {
+	alpha := alphaOrDefault(m.Alpha)
+	m.featureLogProb = make([][]float64, len(m.classes))
+	for k, counts := range m.featureCounts {
+		total := alpha * float64(m.nFeatures)
+		for _, c := range counts {
+			total += c
+		}
+		m.featureLogProb[k] = make([]float64, m.nFeatures)
+		for j, c := range counts {
+			m.featureLogProb[k][j] = math.Log(c+alpha) - math.Log(total)
+		}
+	}
+}
+
+// jointLogLikelihood calcula log P(c) + log P(x|c) para cada clase
+func (m *MultinomialNB[L]) jointLogLikelihood(sample []float64) []float64 {
+	scores := m.classLogPriors()
+	for k := range scores {
+		scores[k] += dotLogProb(sample, m.featureLogProb[k])
+	}
+	return scores
+}
+
+// AdaptPriors re-estima P(c) con EM sobre datos sin etiquetar del dominio
+// de destino, la fija como Priors y la devuelve
+func (m *MultinomialNB[L]) AdaptPriors(X [][]float64, maxIter int, tol float64) ([]float64, error) {
+	return adaptPriors(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood, maxIter, tol)
+}
+
+// Predict realiza predicciones sobre nuevos datos
+func (m *MultinomialNB[L]) Predict(X [][]float64) ([]L, error) {
+	return predictLabels(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// PredictLogProba devuelve log P(c|x) para cada muestra y clase
+func (m *MultinomialNB[L]) PredictLogProba(X [][]float64) ([][]float64, error) {
+	return predictLogProba(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// PredictProba devuelve P(c|x) para cada muestra y clase
+func (m *MultinomialNB[L]) PredictProba(X [][]float64) ([][]float64, error) {
+	return predictProba(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// ComplementNB es la variante de MultinomialNB que estima los parámetros
+// con los conteos del complemento de cada clase; funciona mejor con clases
+// desbalanceadas
+type ComplementNB[L comparable] struct {
+	discreteNB[L]
+
+	// Alpha es el suavizado aditivo; 0 usa el valor por defecto 1 y un
+	// valor negativo desactiva el suavizado
+	Alpha float64
+	// Norm normaliza los pesos de cada clase por su suma
+	Norm bool
+
+	featureLogProb [][]float64 // Pesos por clase y característica
+}
+
+// Fit ajusta el modelo a los conteos de X, descartando ajustes previos
+func (m *ComplementNB[L]) Fit(X [][]float64, y []L) error {
+	if err := checkNBData(X, y, 0); err != nil {
+		return err
+	}
+	m.reset()
+	return m.PartialFit(X, y, nil)
+}
+
+// PartialFit actualiza los conteos con un nuevo lote de datos
+func (m *ComplementNB[L]) PartialFit(X [][]float64, y []L, classes []L) error {
+	if err := checkNBData(X, y, m.nFeatures); err != nil {
+		return err
+	}
+	if err := m.checkPriors(y, classes); err != nil {
+		return err
+	}
+	if err := checkNonNegative(X); err != nil {
+		return err
+	}
+	m.accumulate(X, y, classes)
+	m.updateFeatureLogProb()
+	return nil
+}
+
+// updateFeatureLogProb recalcula los pesos a partir de los conteos del
+// complemento de cada clase
+func (m *ComplementNB[L]) updateFeatureLogProb() {
+	alpha := alphaOrDefault(m.Alpha)
+	featureTotals := make([]float64, m.nFeatures)
+	for _, counts := range m.featureCounts {
+		for j, c := range counts {
+			featureTotals[j] += c
+		}
+	}
+
+	m.featureLogProb = make([][]float64, len(m.classes))
+	for k, counts := range m.featureCounts {
+		complement := make([]float64, m.nFeatures)
+		total := 0.0
+		for j := range complement {
+			complement[j] = featureTotals[j] - counts[j] + alpha
+			total += complement[j]
+		}
+
+		logged := make([]float64, m.nFeatures)
+		loggedSum := 0.0
+		for j := range logged {
+			logged[j] = math.Log(complement[j] / total)
+			loggedSum += logged[j]
+		}
+		for j := range logged {
+			if m.Norm {
+				logged[j] /= loggedSum
+			} else {
+				logged[j] = -logged[j]
+			}
+		}
+		m.featureLogProb[k] = logged
+	}
+}
+
+// jointLogLikelihood calcula la puntuación de cada clase. Como en la
+// formulación original, las probabilidades a priori sólo se usan cuando
+// hay una única clase o cuando se fijaron explícitamente con Priors
+func (m *ComplementNB[L]) jointLogLikelihood(sample []float64) []float64 {
+	scores := make([]float64, len(m.classes))
+	if len(m.classes) == 1 || m.Priors != nil {
+		scores = m.classLogPriors()
+	}
+	for k := range scores {
+		scores[k] += dotLogProb(sample, m.featureLogProb[k])
+	}
+	return scores
+}
+
+// AdaptPriors re-estima P(c) con EM sobre datos sin etiquetar del dominio
+// de destino, la fija como Priors y la devuelve. EM necesita puntuaciones
+// que incluyan log P(c), que jointLogLikelihood omite mientras Priors no
+// esté fijada; después de adaptarlas, las predicciones sí las usan
+func (m *ComplementNB[L]) AdaptPriors(X [][]float64, maxIter int, tol float64) ([]float64, error) {
+	withPriors := func(sample []float64) []float64 {
+		scores := m.jointLogLikelihood(sample)
+		if len(m.classes) > 1 && m.Priors == nil {
+			for k, p := range m.classLogPriors() {
+				scores[k] += p
+			}
+		}
+		return scores
+	}
+	return adaptPriors(&m.naiveBayes, X, m.nFeatures, withPriors, maxIter, tol)
+}
+
+// Predict realiza predicciones sobre nuevos datos
+func (m *ComplementNB[L]) Predict(X [][]float64) ([]L, error) {
+	return predictLabels(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// PredictLogProba devuelve log P(c|x) para cada muestra y clase
+func (m *ComplementNB[L]) PredictLogProba(X [][]float64) ([][]float64, error) {
+	return predictLogProba(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// PredictProba devuelve P(c|x) para cada muestra y clase
+func (m *ComplementNB[L]) PredictProba(X [][]float64) ([][]float64, error) {
+	return predictProba(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// BernoulliNB es un clasificador Naive Bayes para características binarias
+type BernoulliNB[L comparable] struct {
+	discreteNB[L]
+
+	// Alpha es el suavizado aditivo; 0 usa el valor por defecto 1 y un
+	// valor negativo desactiva el suavizado
+	Alpha float64
+	// Binarize es el umbral: los valores mayores se tratan como 1 y el
+	// resto como 0
+	Binarize float64
+	// NoBinarize usa X tal cual, que debe estar ya en [0, 1]; los valores
+	// intermedios cuentan como presencia parcial
+	NoBinarize bool
+
+	featureLogProb    [][]float64 // log P(x_j=1|c)
+	negFeatureLogProb [][]float64 // log P(x_j=0|c)
+}
+
+// binarize convierte X en una matriz de ceros y unos según Binarize, o
+// la devuelve sin cambios con NoBinarize
+func (m *BernoulliNB[L]) binarize(X [][]float64) [][]float64 {
+	if m.NoBinarize {
+		return X
+	}
+	res := make([][]float64, len(X))
+	for i, row := range X {
+		res[i] = m.binarizeRow(row)
+	}
+	return res
+}
+
+// binarizeRow convierte una muestra en ceros y unos según Binarize, o la
+// devuelve sin cambios con NoBinarize
+func (m *BernoulliNB[L]) binarizeRow(row []float64) []float64 {
+	if m.NoBinarize {
+		return row
+	}
+	res := make([]float64, len(row))
+	for j, v := range row {
+		if v > m.Binarize {
+			res[j] = 1
+		}
+	}
+	return res
+}
+
+// Fit ajusta el modelo a los datos, descartando ajustes previos
+func (m *BernoulliNB[L]) Fit(X [][]float64, y []L) error {
+	if err := checkNBData(X, y, 0); err != nil {
+		return err
+	}
+	m.reset()
+	return m.PartialFit(X, y, nil)
+}
+
+// PartialFit actualiza los conteos con un nuevo lote de datos
+func (m *BernoulliNB[L]) PartialFit(X [][]float64, y []L, classes []L) error {
+	if err := checkNBData(X, y, m.nFeatures); err != nil {
+		return err
+	}
+	if err := m.checkPriors(y, classes); err != nil {
+		return err
+	}
+	if m.NoBinarize {
+		if err := checkUnitInterval(X); err != nil {
+			return err
+		}
+	}
+	m.accumulate(m.binarize(X), y, classes)
+	m.updateFeatureLogProb()
+	return nil
+}
+
+// updateFeatureLogProb recalcula log P(x_j=1|c) y log P(x_j=0|c)
+func (m *BernoulliNB[L]) updateFeatureLogProb() {
+	alpha := alphaOrDefault(m.Alpha)
+	m.featureLogProb = make([][]float64, len(m.classes))
+	m.negFeatureLogProb = make([][]float64, len(m.classes))
+	for k, counts := range m.featureCounts {
+		denom := math.Log(m.classCounts[k] + 2*alpha)
+		m.featureLogProb[k] = make([]float64, m.nFeatures)
+		m.negFeatureLogProb[k] = make([]float64, m.nFeatures)
+		for j, c := range counts {
+			m.featureLogProb[k][j] = math.Log(c+alpha) - denom
+			m.negFeatureLogProb[k][j] = math.Log(m.classCounts[k]-c+alpha) - denom
+		}
+	}
+}
+
+// jointLogLikelihood calcula log P(c) + log P(x|c) para cada clase,
+// penalizando también la ausencia de cada característica
+func (m *BernoulliNB[L]) jointLogLikelihood(sample []float64) []float64 {
+	sample = m.binarizeRow(sample)
+	scores := m.classLogPriors()
+	for k := range scores {
+		for j, v := range sample {
+			scores[k] += v*m.featureLogProb[k][j] + (1-v)*m.negFeatureLogProb[k][j]
+		}
+	}
+	return scores
+}
+
+// AdaptPriors re-estima P(c) con EM sobre datos sin etiquetar del dominio
+// de destino, la fija como Priors y la devuelve
+func (m *BernoulliNB[L]) AdaptPriors(X [][]float64, maxIter int, tol float64) ([]float64, error) {
+	return adaptPriors(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood, maxIter, tol)
+}
+
+// Predict realiza predicciones sobre nuevos datos
+func (m *BernoulliNB[L]) Predict(X [][]float64) ([]L, error) {
+	return predictLabels(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// PredictLogProba devuelve log P(c|x) para cada muestra y clase
+func (m *BernoulliNB[L]) PredictLogProba(X [][]float64) ([][]float64, error) {
+	return predictLogProba(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// PredictProba devuelve P(c|x) para cada muestra y clase
+func (m *BernoulliNB[L]) PredictProba(X [][]float64) ([][]float64, error) {
+	return predictProba(&m.naiveBayes, X, m.nFeatures, m.jointLogLikelihood)
+}
+
+// CategoricalNB es un clasificador Naive Bayes para características
+// categóricas codificadas como enteros 0..K-1 (por ejemplo con
+// LabelEncoder)
+type CategoricalNB[L comparable] struct {
+	naiveBayes[L]
+
+	// Alpha es el suavizado aditivo; 0 usa el valor por defecto 1 y un
+	// valor negativo desactiva el suavizado
+	Alpha float64
+
+	categoryCounts [][][]float64 // Conteos por clase, característica y categoría
+	nCategories    []int         // Cantidad de categorías vistas por característica
+}
+
+// reset elimina clases y conteos acumulados
+func (m *CategoricalNB[L]) reset() {
+	m.naiveBayes.reset()
+	m.categoryCounts = nil
+	m.nCategories = nil
+}
+
+// Fit ajusta el modelo a los datos, descartando ajustes previos
+func (m *CategoricalNB[L]) Fit(X [][]int, y []L) error {
+	if err := checkNBData(X, y, 0); err != nil {
+		return err
+	}
+	m.reset()
+	return m.PartialFit(X, y, nil)
+}
+
+// PartialFit actualiza los conteos con un nuevo lote de datos. Las
+// categorías nuevas amplían el vocabulario de cada característica
+func (m *CategoricalNB[L]) PartialFit(X [][]int, y []L, classes []L) error {
+	if err := checkNBData(X, y, len(m.nCategories)); err != nil {
+		return err
+	}
+	if err := m.checkPriors(y, classes); err != nil {
+		return err
+	}
+	for _, row := range X {
+		for _, v := range row {
+			if v < 0 {
+				return errors.New("las categorías de X deben ser enteros no negativos")
+			}
+		}
+	}
+
+	if m.nCategories == nil {
+		m.nCategories = make([]int, len(X[0]))
+	}
+	for _, row := range X {
+		for j, v := range row {
+			if v+1 > m.nCategories[j] {
+				m.nCategories[j] = v + 1
+			}
+		}
+	}
+
+	indices := m.countLabels(y, classes)
+	for len(m.categoryCounts) < len(m.classes) {
+		m.categoryCounts = append(m.categoryCounts, make([][]float64, len(m.nCategories)))
+	}
+	for k := range m.categoryCounts {
+		for j, n := range m.nCategories {
+			for len(m.categoryCounts[k][j]) < n {
+				m.categoryCounts[k][j] = append(m.categoryCounts[k][j], 0)
+			}
+		}
+	}
+
+	for i, k := range indices {
+		for j, v := range X[i] {
+			m.categoryCounts[k][j][v]++
+		}
+	}
+	return nil
+}
+
+// jointLogLikelihood calcula log P(c) + log P(x|c) para cada clase. Las
+// categorías no vistas en el entrenamiento sólo reciben el suavizado
+func (m *CategoricalNB[L]) jointLogLikelihood(sample []int) []float64 {
+	alpha := alphaOrDefault(m.Alpha)
+	scores := m.classLogPriors()
+	for k := range scores {
+		for j, v := range sample {
+			count := 0.0
+			if v >= 0 && v < m.nCategories[j] {
+				count = m.categoryCounts[k][j][v]
+			}
+			denom := m.classCounts[k] + alpha*float64(m.nCategories[j])
+			scores[k] += math.Log(count+alpha) - math.Log(denom)
+		}
+	}
+	return scores
+}
+
+// AdaptPriors re-estima P(c) con EM sobre datos sin etiquetar del dominio
+// de destino, la fija como Priors y la devuelve
+func (m *CategoricalNB[L]) AdaptPriors(X [][]int, maxIter int, tol float64) ([]float64, error) {
+	return adaptPriors(&m.naiveBayes, X, len(m.nCategories), m.jointLogLikelihood, maxIter, tol)
+}
+
+// Predict realiza predicciones sobre nuevos datos
+func (m *CategoricalNB[L]) Predict(X [][]int) ([]L, error) {
+	return predictLabels(&m.naiveBayes, X, len(m.nCategories), m.jointLogLikelihood)
+}
+
+// PredictLogProba devuelve log P(c|x) para cada muestra y clase
+func (m *CategoricalNB[L]) PredictLogProba(X [][]int) ([][]float64, error) {
+	return predictLogProba(&m.naiveBayes, X, len(m.nCategories), m.jointLogLikelihood)
+}
+
+// PredictProba devuelve P(c|x) para cada muestra y clase
+func (m *CategoricalNB[L]) PredictProba(X [][]int) ([][]float64, error) {
+	return predictProba(&m.naiveBayes, X, len(m.nCategories), m.jointLogLikelihood)
+}
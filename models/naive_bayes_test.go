@@ -0,0 +1,523 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// twoClassData devuelve un problema separable de dos clases
+func twoClassData() ([][]float64, []string) {
+	X := [][]float64{{1, 2}, {1, 3}, {2, 2}, {6, 7}, {7, 6}, {7, 8}}
+	y := []string{"a", "a", "a", "b", "b", "b"}
+	return X, y
+}
+
+func TestNaiveBayesPriorsAssignedAfterFit(t *testing.T) {
+	X, y := twoClassData()
+	for _, priors := range [][]float64{{0.2, 0.3, 0.5}, {1}, {0.7, 0.7}} {
+		gnb := &GaussianNB[string]{}
+		if err := gnb.Fit(X, y); err != nil {
+			t.Fatal(err)
+		}
+		gnb.Priors = priors
+		if _, err := gnb.Predict(X); err == nil {
+			t.Errorf("Priors %v: Predict expected an error", priors)
+		}
+		if _, err := gnb.PredictProba(X); err == nil {
+			t.Errorf("Priors %v: PredictProba expected an error", priors)
+		}
+		if _, err := gnb.ClassPriors(); err == nil {
+			t.Errorf("Priors %v: ClassPriors expected an error", priors)
+		}
+		if _, err := gnb.AdaptPriors(X, 0, 0); err == nil {
+			t.Errorf("Priors %v: AdaptPriors expected an error", priors)
+		}
+	}
+}
+
+func TestNaiveBayesValidPriorsAssignedAfterFit(t *testing.T) {
+	X, y := twoClassData()
+	m := &MultinomialNB[string]{}
+	if err := m.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+	m.Priors = []float64{0.25, 0.75}
+	priors, err := m.ClassPriors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(priors[0]-0.25) > 1e-12 || math.Abs(priors[1]-0.75) > 1e-12 {
+		t.Errorf("got priors %v, expected [0.25 0.75]", priors)
+	}
+}
+
+func TestNaiveBayesPredictBeforeFit(t *testing.T) {
+	if _, err := (&BernoulliNB[int]{}).Predict([][]float64{{1}}); err == nil {
+		t.Error("expected an error from an untrained model")
+	}
+}
+
+func TestNaiveBayesPredictChecksFeatureCount(t *testing.T) {
+	X, y := twoClassData()
+	wide := [][]float64{{1, 2, 3}}
+	type model interface {
+		Fit([][]float64, []string) error
+		Predict([][]float64) ([]string, error)
+		PredictLogProba([][]float64) ([][]float64, error)
+		PredictProba([][]float64) ([][]float64, error)
+	}
+	for _, m := range []model{&GaussianNB[string]{}, &MultinomialNB[string]{}, &ComplementNB[string]{}, &BernoulliNB[string]{}} {
+		if err := m.Fit(X, y); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Predict(wide); err == nil {
+			t.Errorf("%T: Predict expected an error for 3 features", m)
+		}
+		if _, err := m.PredictLogProba(wide); err == nil {
+			t.Errorf("%T: PredictLogProba expected an error for 3 features", m)
+		}
+		if _, err := m.PredictProba(X[:1]); err != nil {
+			t.Errorf("%T: PredictProba: %v", m, err)
+		}
+		if _, err := m.PredictProba([][]float64{{1}}); err == nil {
+			t.Errorf("%T: PredictProba expected an error for 1 feature", m)
+		}
+	}
+
+	c := &CategoricalNB[string]{}
+	if err := c.Fit([][]int{{0, 1}, {1, 0}}, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Predict([][]int{{0}}); err == nil {
+		t.Error("CategoricalNB: Predict expected an error for 1 feature")
+	}
+	if _, err := c.AdaptPriors([][]int{{0, 1, 2}}, 0, 0); err == nil {
+		t.Error("CategoricalNB: AdaptPriors expected an error for 3 features")
+	}
+}
+
+func TestMultinomialNBAlpha(t *testing.T) {
+	X := [][]float64{{2, 0}, {3, 0}, {0, 2}, {0, 3}}
+	y := []string{"a", "a", "b", "b"}
+	probaWith := func(alpha float64) []float64 {
+		m := &MultinomialNB[string]{Alpha: alpha}
+		if err := m.Fit(X, y); err != nil {
+			t.Fatal(err)
+		}
+		proba, err := m.PredictProba([][]float64{{1, 0}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return proba[0]
+	}
+
+	// 0 es el suavizado de Laplace
+	if def, laplace := probaWith(0), probaWith(1); def[0] != laplace[0] {
+		t.Errorf("Alpha 0 gives %v, expected the Laplace result %v", def, laplace)
+	}
+	// Sin suavizado la clase b no puede producir la primera característica
+	if proba := probaWith(-1); proba[0] < 1-1e-9 || math.IsNaN(proba[1]) {
+		t.Errorf("Alpha -1 gives %v, expected [1 0]", proba)
+	}
+}
+
+func TestBernoulliNBNoBinarize(t *testing.T) {
+	X := [][]float64{{1, 0}, {1, 0.2}, {0, 1}, {0.1, 1}}
+	y := []string{"a", "a", "b", "b"}
+
+	m := &BernoulliNB[string]{NoBinarize: true}
+	if err := m.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+	// Los valores intermedios cuentan como presencia parcial
+	full, err := m.PredictProba([][]float64{{1, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial, err := m.PredictProba([][]float64{{0.5, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(full[0][0] > partial[0][0] && partial[0][0] > 0.5) {
+		t.Errorf("P(a|x) is %v for x=[1 0] and %v for x=[0.5 0], expected both above 0.5 and decreasing", full[0][0], partial[0][0])
+	}
+
+	// Con ceros y unos coincide con la versión binarizada
+	binary := [][]float64{{1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	plain, raw := &BernoulliNB[string]{}, &BernoulliNB[string]{NoBinarize: true}
+	if err := plain.Fit(binary, y); err != nil {
+		t.Fatal(err)
+	}
+	if err := raw.Fit(binary, y); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := plain.PredictLogProba(binary)
+	got, _ := raw.PredictLogProba(binary)
+	for i := range want {
+		for k := range want[i] {
+			if math.Abs(got[i][k]-want[i][k]) > 1e-12 {
+				t.Errorf("row %d class %d: %v, expected %v", i, k, got[i][k], want[i][k])
+			}
+		}
+	}
+
+	if err := (&BernoulliNB[string]{NoBinarize: true}).Fit([][]float64{{2, 0}}, []string{"a"}); err == nil {
+		t.Error("expected an error for a value outside [0, 1]")
+	}
+}
+
+func TestComplementNBAdaptPriors(t *testing.T) {
+	X, y := twoClassData()
+	m := &ComplementNB[string]{}
+	if err := m.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+	// El dominio de destino tiene sobre todo muestras de la clase b
+	target := [][]float64{{1, 2}, {6, 7}, {7, 6}, {7, 8}, {6, 6}, {8, 7}, {7, 7}, {6, 8}}
+	priors, err := m.AdaptPriors(target, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(priors[0]+priors[1]-1) > 1e-9 || priors[1] < 0.75 {
+		t.Errorf("got priors %v, expected most of the mass on b", priors)
+	}
+	if m.Priors == nil {
+		t.Error("AdaptPriors did not set Priors")
+	}
+	if _, err := m.AdaptPriors([][]float64{{1}}, 0, 0); err == nil {
+		t.Error("expected an error for 1 feature")
+	}
+}
+
+func TestGaussianNBAdapterRejectsUnhashableLabels(t *testing.T) {
+	X, _ := twoClassData()
+	type wrapper struct{ v interface{} }
+	for _, bad := range []interface{}{[]int{1}, map[string]int{}, wrapper{[]int{1}}} {
+		y := []interface{}{"a", "a", "a", "b", "b", bad}
+
+		if err := (&GaussianNBAdapter{}).Fit(X, y); err == nil {
+			t.Errorf("%T: Fit expected an error", bad)
+		}
+		if err := (&GaussianNBAdapter{}).PartialFit(X, y, nil); err == nil {
+			t.Errorf("%T: PartialFit expected an error for y", bad)
+		}
+		valid := []interface{}{"a", "a", "a", "b", "b", "b"}
+		if err := (&GaussianNBAdapter{}).PartialFit(X, valid, []interface{}{"a", bad}); err == nil {
+			t.Errorf("%T: PartialFit expected an error for classes", bad)
+		}
+
+		// Un modelo construido sin pasar por el adaptador
+		other := &GaussianNBAdapter{}
+		other.classes = []interface{}{bad}
+		if err := (&GaussianNBAdapter{}).Merge(other); err == nil {
+			t.Errorf("%T: Merge expected an error", bad)
+		}
+	}
+}
+
+func TestGaussianNBAdapterMerge(t *testing.T) {
+	X, labels := twoClassData()
+	y := make([]interface{}, len(labels))
+	for i, label := range labels {
+		y[i] = label
+	}
+
+	whole := &GaussianNBAdapter{}
+	if err := whole.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+	first, second := &GaussianNBAdapter{}, &GaussianNBAdapter{}
+	if err := first.Fit(X[:2], y[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Fit(X[2:], y[2:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Merge(second); err != nil {
+		t.Fatal(err)
+	}
+
+	want, got := whole.PredictProba(X), first.PredictProba(X)
+	for i := range want {
+		for k := range want[i] {
+			if math.Abs(got[i][k]-want[i][k]) > 1e-9 {
+				t.Errorf("row %d class %d: merged %v, expected %v", i, k, got[i][k], want[i][k])
+			}
+		}
+	}
+}
+
+func TestGaussianNBAdapterKeepsOriginalAPI(t *testing.T) {
+	X := [][]float64{{1, 2}, {1, 3}, {2, 2}, {3, 3}, {3, 4}}
+	y := []interface{}{0, 0, 1, 1, 0}
+	model := &GaussianNBAdapter{}
+	if err := model.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+	var predictions []interface{} = model.Predict(X)
+	if len(predictions) != len(X) {
+		t.Fatalf("got %d predictions, expected %d", len(predictions), len(X))
+	}
+	want, err := model.GaussianNB.Predict(X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if predictions[i] != want[i] {
+			t.Errorf("row %d: %v, expected %v", i, predictions[i], want[i])
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a row with 3 features, as before")
+		}
+	}()
+	model.Predict([][]float64{{1, 2, 3}})
+}
+
+func TestGaussianNBFarSample(t *testing.T) {
+	X, y := twoClassData()
+	gnb := &GaussianNB[string]{}
+	if err := gnb.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+	// Lejos de ambas clases las densidades valen 0 en el espacio lineal,
+	// pero las probabilidades en escala logarítmica siguen normalizadas
+	for _, sample := range [][]float64{{1e6, -1e6}, {-1e8, 1e8}} {
+		logProba, err := gnb.PredictLogProba([][]float64{sample})
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := 0.0
+		for _, lp := range logProba[0] {
+			if math.IsNaN(lp) || math.IsInf(lp, 0) {
+				t.Fatalf("%v: got log-probabilities %v, expected finite values", sample, logProba[0])
+			}
+			total += math.Exp(lp)
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Errorf("%v: probabilities add up to %v, expected 1", sample, total)
+		}
+	}
+}
+
+func TestGaussianNBVarSmoothing(t *testing.T) {
+	// La segunda característica es constante dentro de la clase a
+	X := [][]float64{{0, 5}, {2, 5}, {10, 0}, {14, 2}}
+	y := []string{"a", "a", "b", "b"}
+	// La mayor varianza entre las características es la de la primera
+	maxVariance := 0.0
+	mean := (0 + 2 + 10 + 14) / 4.0
+	for _, row := range X {
+		maxVariance += (row[0] - mean) * (row[0] - mean) / 4
+	}
+
+	for _, smoothing := range []float64{0, 1e-3, 0.5} {
+		gnb := &GaussianNB[string]{VarSmoothing: smoothing}
+		if err := gnb.Fit(X, y); err != nil {
+			t.Fatal(err)
+		}
+		want := smoothing
+		if want == 0 {
+			want = defaultVarSmoothing
+		}
+		want *= maxVariance
+		if got := gnb.featureStats[0][1].StdDev; math.Abs(got*got-want) > 1e-12*maxVariance {
+			t.Errorf("VarSmoothing %v: the constant feature has variance %v, expected %v", smoothing, got*got, want)
+		}
+		// En la clase b la segunda característica tiene varianza 1 más el piso
+		if got := gnb.featureStats[1][1].StdDev; math.Abs(got*got-(1+want)) > 1e-9 {
+			t.Errorf("VarSmoothing %v: got variance %v, expected %v", smoothing, got*got, 1+want)
+		}
+	}
+}
+
+// gaussianData devuelve muestras de tres clases desplazadas en offset
+func gaussianData(n int, offset float64) ([][]float64, []int) {
+	X := make([][]float64, n)
+	y := make([]int, n)
+	for i := range X {
+		y[i] = i % 3
+		X[i] = []float64{offset + float64(y[i]) + 0.1*float64(i%7), offset - 0.05*float64(i%5)}
+	}
+	return X, y
+}
+
+// sameFeatureStats compara las estadísticas de dos modelos con tolerancia
+// relativa
+func sameFeatureStats(t *testing.T, got, want *GaussianNB[int], tol float64) {
+	t.Helper()
+	if len(got.Classes()) != len(want.Classes()) {
+		t.Fatalf("got classes %v, expected %v", got.Classes(), want.Classes())
+	}
+	for k := range want.featureStats {
+		if got.classes[k] != want.classes[k] || got.classCounts[k] != want.classCounts[k] {
+			t.Errorf("class %d: got %v with %v samples, expected %v with %v", k,
+				got.classes[k], got.classCounts[k], want.classes[k], want.classCounts[k])
+		}
+		for j, w := range want.featureStats[k] {
+			g := got.featureStats[k][j]
+			if math.Abs(g.Mean-w.Mean) > tol*math.Max(1, math.Abs(w.Mean)) ||
+				math.Abs(g.StdDev-w.StdDev) > tol*w.StdDev {
+				t.Errorf("class %d feature %d: got %+v, expected %+v", k, j, g, w)
+			}
+		}
+	}
+}
+
+func TestGaussianNBPartialFitMatchesFit(t *testing.T) {
+	X, y := gaussianData(60, 0)
+	whole := &GaussianNB[int]{}
+	if err := whole.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+	chunked := &GaussianNB[int]{}
+	for start := 0; start < len(X); start += 7 {
+		end := min(start+7, len(X))
+		if err := chunked.PartialFit(X[start:end], y[start:end], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sameFeatureStats(t, chunked, whole, 1e-12)
+}
+
+func TestGaussianNBMergeMatchesFit(t *testing.T) {
+	X, y := gaussianData(60, 0)
+	whole := &GaussianNB[int]{}
+	if err := whole.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+	first, second := &GaussianNB[int]{}, &GaussianNB[int]{}
+	if err := first.Fit(X[:25], y[:25]); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Fit(X[25:], y[25:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Merge(second); err != nil {
+		t.Fatal(err)
+	}
+	sameFeatureStats(t, first, whole, 1e-12)
+}
+
+func TestGaussianNBLargeOffset(t *testing.T) {
+	// Con un desplazamiento de 1e9 la fórmula E[x²] - E[x]² pierde toda
+	// la precisión; los momentos centrados no
+	X, y := gaussianData(60, 1e9)
+	centered, _ := gaussianData(60, 0)
+	want := &GaussianNB[int]{}
+	if err := want.Fit(centered, y); err != nil {
+		t.Fatal(err)
+	}
+	got := &GaussianNB[int]{}
+	for start := 0; start < len(X); start += 11 {
+		end := min(start+11, len(X))
+		if err := got.PartialFit(X[start:end], y[start:end], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for k := range want.featureStats {
+		for j, w := range want.featureStats[k] {
+			g := got.featureStats[k][j]
+			if !(g.StdDev > 0) || math.Abs(g.StdDev-w.StdDev) > 1e-6*w.StdDev {
+				t.Errorf("class %d feature %d: standard deviation %v, expected %v", k, j, g.StdDev, w.StdDev)
+			}
+		}
+	}
+}
+
+func TestGaussianNBMergeChecksModels(t *testing.T) {
+	X, y := gaussianData(30, 0)
+	base := func() *GaussianNB[int] {
+		gnb := &GaussianNB[int]{}
+		if err := gnb.Fit(X[:12], y[:12]); err != nil {
+			t.Fatal(err)
+		}
+		return gnb
+	}
+
+	wide := &GaussianNB[int]{}
+	if err := wide.Fit([][]float64{{1, 2, 3}}, []int{0}); err != nil {
+		t.Fatal(err)
+	}
+	gnb := base()
+	if err := gnb.Merge(wide); err == nil {
+		t.Error("expected an error for 3 features")
+	}
+
+	// Las priors fijadas para tres clases no sirven con una cuarta
+	other := &GaussianNB[int]{}
+	if err := other.Fit([][]float64{{1, 2}}, []int{3}); err != nil {
+		t.Fatal(err)
+	}
+	gnb = base()
+	gnb.Priors = []float64{0.2, 0.3, 0.5}
+	if err := gnb.Merge(other); err == nil {
+		t.Error("expected an error for priors of 3 classes")
+	}
+	sameFeatureStats(t, gnb, base(), 0)
+}
+
+func TestGaussianNBAdaptPriors(t *testing.T) {
+	// Entrenamos con clases balanceadas
+	rng := rand.New(rand.NewSource(1))
+	sample := func(class int) []float64 {
+		center := float64(3 * class)
+		return []float64{center + rng.NormFloat64(), center + rng.NormFloat64()}
+	}
+	var X [][]float64
+	var y []int
+	for i := 0; i < 200; i++ {
+		X = append(X, sample(i%2))
+		y = append(y, i%2)
+	}
+	gnb := &GaussianNB[int]{}
+	if err := gnb.Fit(X, y); err != nil {
+		t.Fatal(err)
+	}
+
+	// El dominio de destino tiene un 90% de la clase 0
+	var target [][]float64
+	for i := 0; i < 1000; i++ {
+		class := 0
+		if i%10 == 9 {
+			class = 1
+		}
+		target = append(target, sample(class))
+	}
+	priors, err := gnb.AdaptPriors(target, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(priors[0]-0.9) > 0.03 || math.Abs(priors[0]+priors[1]-1) > 1e-9 {
+		t.Errorf("got priors %v, expected about [0.9 0.1]", priors)
+	}
+	if gnb.Priors == nil || gnb.Priors[0] != priors[0] {
+		t.Errorf("Priors is %v, expected %v", gnb.Priors, priors)
+	}
+}
+
+func TestGaussianNBClassesInFirstAppearanceOrder(t *testing.T) {
+	X := [][]float64{{5}, {9}, {1}, {5.5}, {8.5}, {1.5}}
+	y := []string{"m", "z", "a", "m", "z", "a"}
+	for run := 0; run < 5; run++ {
+		gnb := &GaussianNB[string]{}
+		if err := gnb.Fit(X, y); err != nil {
+			t.Fatal(err)
+		}
+		if classes := gnb.Classes(); !reflect.DeepEqual(classes, []string{"m", "z", "a"}) {
+			t.Fatalf("got classes %v, expected [m z a]", classes)
+		}
+		// Las columnas de PredictProba siguen el mismo orden
+		proba, err := gnb.PredictProba([][]float64{{9}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if argmax(proba[0]) != 1 {
+			t.Fatalf("got probabilities %v, expected the second column for z", proba[0])
+		}
+	}
+}
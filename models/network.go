@@ -0,0 +1,541 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// defaultBatchSize is the mini-batch size set by the network constructors
+const defaultBatchSize = 32
+
+// Network holds the layer stack, the training configuration and the
+// mini-batch training loop shared by the neural network models. The
+// models embed it and supply the loss and, for classifiers, the accuracy.
+// Layers can be edited after construction, for instance to insert
+// DropoutLayer or BatchNormLayer stages between the dense layers.
+type Network struct {
+	InputNodes       int
+	HiddenLayerSizes []int
+	OutputNodes      int
+	LearningRate     float64
+	Alpha            float64 // L2 penalty on the weights
+
+	BatchSize int  // Samples per gradient step; 0 or more than len(X) uses the whole set
+	Shuffle   bool // Shuffle the samples at the start of every epoch
+
+	Optimizer Optimizer            // nil uses plain SGD with LearningRate
+	Schedule  LearningRateSchedule // Optional per-epoch learning rate
+
+	// ValidationFraction holds out this fraction of the samples to compute
+	// the validation metrics of the History
+	ValidationFraction float64
+
+	// EarlyStopping stops training once the monitored loss (validation loss
+	// when ValidationFraction > 0, training loss otherwise) has not improved
+	// by more than MinDelta for Patience epochs
+	EarlyStopping      bool
+	Patience           int
+	MinDelta           float64
+	RestoreBestWeights bool // Go back to the weights of the best epoch when training ends
+
+	Callbacks []Callback // Notified of epoch and batch progress during Fit
+
+	// Workers shards every mini-batch across this many goroutines, each
+	// computing the gradients of its shard on a replica of the layers; the
+	// gradients are reduced in a fixed order, so seeded runs stay
+	// reproducible. 0 or 1 trains on the calling goroutine. A
+	// BatchNormLayer normalizes each shard with the statistics of that
+	// shard alone, so its gradients differ from training with one worker,
+	// and only the first shard updates the running statistics
+	Workers int
+
+	// Hogwild makes the Workers train on separate mini-batches instead and
+	// apply plain SGD steps to the shared parameters as soon as each one
+	// is done, with no barrier between batches. Unlike lock-free Hogwild!,
+	// a step still waits for the forward and backward passes reading the
+	// parameter it writes, see hogwildEpoch. The Optimizer only provides
+	// the learning rate
+	Hogwild bool
+
+	Layers []Layer
+
+	rng *rand.Rand // Set by Initialize; nil uses the global math/rand source
+}
+
+// newNetwork builds dense layers with the given hidden activation and a
+// linear output layer, initialized from src unless it is nil
+func newNetwork(inputNodes int, hiddenLayerSizes []int, outputNodes int, learningRate float64, hidden Activation, src rand.Source) Network {
+	nn := Network{
+		InputNodes:       inputNodes,
+		HiddenLayerSizes: append([]int(nil), hiddenLayerSizes...),
+		OutputNodes:      outputNodes,
+		LearningRate:     learningRate,
+		BatchSize:        defaultBatchSize,
+		Shuffle:          true,
+	}
+
+	inputs := inputNodes
+	for _, size := range hiddenLayerSizes {
+		nn.Layers = append(nn.Layers, NewDenseLayer(inputs, size, hidden))
+		inputs = size
+	}
+	nn.Layers = append(nn.Layers, NewDenseLayer(inputs, outputNodes, Identity{}))
+	if src != nil {
+		nn.Initialize(src)
+	}
+	return nn
+}
+
+// SetActivation selects a registered activation by name for the dense
+// layer at the given index (0 is the first hidden layer)
+func (nn *Network) SetActivation(layer int, name string) error {
+	if layer < 0 || layer >= len(nn.Layers) {
+		return fmt.Errorf("layer index %d out of range [0, %d)", layer, len(nn.Layers))
+	}
+	dense, ok := nn.Layers[layer].(*DenseLayer)
+	if !ok {
+		return fmt.Errorf("layer %d has no activation", layer)
+	}
+	activation, err := GetActivation(name)
+	if err != nil {
+		return err
+	}
+	dense.Activation = activation
+	return nil
+}
+
+// SetInitializer selects the weight and bias initializers of the dense
+// layer at the given index and redraws its parameters. A nil initializer
+// keeps the current one
+func (nn *Network) SetInitializer(layer int, weights, bias Initializer) error {
+	if layer < 0 || layer >= len(nn.Layers) {
+		return fmt.Errorf("layer index %d out of range [0, %d)", layer, len(nn.Layers))
+	}
+	dense, ok := nn.Layers[layer].(*DenseLayer)
+	if !ok {
+		return fmt.Errorf("layer %d has no initializers", layer)
+	}
+	if weights != nil {
+		dense.WeightInit = weights
+	}
+	if bias != nil {
+		dense.BiasInit = bias
+	}
+	dense.Initialize(nn.rng)
+	return nil
+}
+
+// Initialize redraws the parameters of every layer from src and keeps it
+// for the validation split, the shuffling and dropout, so that training
+// with the same seed is reproducible. A nil src goes back to the global
+// math/rand source
+func (nn *Network) Initialize(src rand.Source) {
+	nn.rng = nil
+	if src != nil {
+		nn.rng = rand.New(src)
+	}
+	for _, layer := range nn.Layers {
+		if l, ok := layer.(initializableLayer); ok {
+			l.Initialize(nn.rng)
+		}
+	}
+	nn.shareRand()
+}
+
+// shareRand hands the generator of the network to the layers that draw
+// random numbers while training
+func (nn *Network) shareRand() {
+	for _, layer := range nn.Layers {
+		if l, ok := layer.(randomLayer); ok {
+			l.setRand(nn.rng)
+		}
+	}
+}
+
+// perm returns a random permutation of [0, n)
+func (nn *Network) perm(n int) []int {
+	if nn.rng == nil {
+		return rand.Perm(n)
+	}
+	return nn.rng.Perm(n)
+}
+
+// shuffle shuffles the indices in place
+func (nn *Network) shuffle(indices []int) {
+	swap := func(i, j int) { indices[i], indices[j] = indices[j], indices[i] }
+	if nn.rng == nil {
+		rand.Shuffle(len(indices), swap)
+		return
+	}
+	nn.rng.Shuffle(len(indices), swap)
+}
+
+// parameters collects the trainable parameters of every layer
+func (nn *Network) parameters() []*Parameter {
+	var params []*Parameter
+	for _, layer := range nn.Layers {
+		params = append(params, layer.Parameters()...)
+	}
+	return params
+}
+
+// optimizer returns the configured optimizer or a plain SGD one using
+// LearningRate
+func (nn *Network) optimizer() Optimizer {
+	if nn.Optimizer == nil {
+		return NewSGD(nn.LearningRate, 0, false)
+	}
+	return nn.Optimizer
+}
+
+// ----------- Utility matrix operations -----------
+
+// rowsToDense copies a slice of samples into a matrix with one sample per
+// row
+func rowsToDense(X [][]float64) *mat.Dense {
+	cols := len(X[0])
+	data := make([]float64, 0, len(X)*cols)
+	for _, row := range X {
+		data = append(data, row...)
+	}
+	return mat.NewDense(len(X), cols, data)
+}
+
+// denseToRows copies a matrix into a slice of rows
+func denseToRows(m *mat.Dense) [][]float64 {
+	rows, _ := m.Dims()
+	result := make([][]float64, rows)
+	for i := range result {
+		result[i] = append([]float64(nil), m.RawRowView(i)...)
+	}
+	return result
+}
+
+// selectRows gathers the given rows of m into a new matrix
+func selectRows(m *mat.Dense, indices []int) *mat.Dense {
+	_, cols := m.Dims()
+	result := mat.NewDense(len(indices), cols, nil)
+	for i, idx := range indices {
+		result.SetRow(i, m.RawRowView(idx))
+	}
+	return result
+}
+
+// ----------- Training -----------
+
+// accuracyFunc counts the correctly predicted rows of a batch
+type accuracyFunc func(outputs, targets *mat.Dense) int
+
+// checkData verifies that X and Y are non-empty, have the same number of
+// rows and match the input and output sizes of the network
+func (nn *Network) checkData(X, Y [][]float64) error {
+	if len(X) == 0 || len(Y) == 0 {
+		return errors.New("X or Y are empty")
+	}
+	if len(X) != len(Y) {
+		return errors.New("X and Y have different lengths")
+	}
+	for i := range Y {
+		if len(Y[i]) != nn.OutputNodes {
+			return fmt.Errorf("row %d of Y has %d outputs, expected %d", i, len(Y[i]), nn.OutputNodes)
+		}
+	}
+	return nn.checkInputs(X)
+}
+
+// checkInputs verifies the layer stack, the width of every row of X and
+// the ID columns read by embeddings, so that bad input is reported
+// instead of reaching the layers
+func (nn *Network) checkInputs(X [][]float64) error {
+	if err := nn.checkLayers(); err != nil {
+		return err
+	}
+	if len(X) == 0 {
+		return errors.New("X is empty")
+	}
+	for i, row := range X {
+		if len(row) != nn.InputNodes {
+			return fmt.Errorf("row %d of X has %d features, expected %d", i, len(row), nn.InputNodes)
+		}
+	}
+	return nn.checkIDs(X)
+}
+
+// checkLayers follows the row width from InputNodes through the layers,
+// verifying that each layer accepts the width of the previous one and
+// that its settings, such as the Rate of a DropoutLayer, are valid. The
+// check stops at the first layer that does not implement shapedLayer,
+// whose output width is unknown
+func (nn *Network) checkLayers() error {
+	width := nn.InputNodes
+	for i, layer := range nn.Layers {
+		shaped, ok := layer.(shapedLayer)
+		if !ok {
+			return nil
+		}
+		var err error
+		if width, err = shaped.outputWidth(width); err != nil {
+			return fmt.Errorf("layer %d (%T): %w", i, layer, err)
+		}
+	}
+	return nil
+}
+
+// fit runs the training loop with the given loss. accuracy may be nil for
+// models without a notion of accuracy, leaving it out of the History.
+// Training stops between mini-batches once ctx is done, and the History
+// so far is returned with ctx.Err(). A callback returning ErrStopTraining
+// ends training without an error, any other error aborts it.
+func (nn *Network) fit(ctx context.Context, X, Y [][]float64, epochs int, loss Loss, accuracy accuracyFunc) (*History, error) {
+	if err := nn.checkData(X, Y); err != nil {
+		return nil, err
+	}
+	if nn.ValidationFraction < 0 || nn.ValidationFraction >= 1 {
+		return nil, fmt.Errorf("ValidationFraction is %v, expected a value in [0, 1)", nn.ValidationFraction)
+	}
+	nValidation := int(nn.ValidationFraction * float64(len(X)))
+	if nn.ValidationFraction > 0 && (nValidation == 0 || nValidation == len(X)) {
+		return nil, errors.New("ValidationFraction leaves no samples for training or validation")
+	}
+
+	nn.shareRand()
+
+	// Hold out a random validation split
+	order := nn.perm(len(X))
+	inputs := rowsToDense(X)
+	targets := rowsToDense(Y)
+	var validationInputs, validationTargets *mat.Dense
+	if nValidation > 0 {
+		validationInputs = selectRows(inputs, order[:nValidation])
+		validationTargets = selectRows(targets, order[:nValidation])
+		order = order[nValidation:]
+	}
+
+	batchSize := nn.BatchSize
+	if batchSize <= 0 || batchSize > len(order) {
+		batchSize = len(order)
+	}
+
+	history := &History{BestEpoch: -1}
+	stopper := earlyStopper{patience: nn.Patience, minDelta: nn.MinDelta, bestLoss: math.Inf(1)}
+	var bestWeights [][]float64
+	defer func() {
+		if nn.RestoreBestWeights && bestWeights != nil {
+			nn.restore(bestWeights)
+		}
+	}()
+
+	workers, err := nn.newWorkers()
+	if err != nil {
+		return nil, err
+	}
+
+	// The schedule changes the optimizer's rate in place: restore it so the
+	// next run starts from the same base rate instead of the decayed one
+	optimizer := nn.optimizer()
+	initialRate := optimizer.LearningRate()
+	defer optimizer.SetLearningRate(initialRate)
+	lastLoss := math.NaN()
+	for e := 0; e < epochs; e++ {
+		if nn.Schedule != nil {
+			optimizer.SetLearningRate(nn.Schedule.LearningRate(e, initialRate, lastLoss))
+		}
+		if err := notifyEpochBegin(nn.Callbacks, e); err != nil {
+			return history, stopError(history, err)
+		}
+		if nn.Shuffle {
+			nn.shuffle(order)
+		}
+
+		epochLoss, epochCorrect, err := nn.epoch(ctx, workers, inputs, targets, order, batchSize, optimizer, loss, accuracy)
+		if err != nil {
+			return history, stopError(history, err)
+		}
+
+		logs := EpochLogs{
+			Loss:               epochLoss / float64(len(order)),
+			Accuracy:           math.NaN(),
+			ValidationLoss:     math.NaN(),
+			ValidationAccuracy: math.NaN(),
+			LearningRate:       optimizer.LearningRate(),
+		}
+		history.Loss = append(history.Loss, logs.Loss)
+		if accuracy != nil {
+			logs.Accuracy = float64(epochCorrect) / float64(len(order))
+			history.Accuracy = append(history.Accuracy, logs.Accuracy)
+		}
+
+		monitored := logs.Loss
+		if validationInputs != nil {
+			logs.ValidationLoss, logs.ValidationAccuracy = nn.evaluate(validationInputs, validationTargets, loss, accuracy)
+			history.ValidationLoss = append(history.ValidationLoss, logs.ValidationLoss)
+			if accuracy != nil {
+				history.ValidationAccuracy = append(history.ValidationAccuracy, logs.ValidationAccuracy)
+			}
+			monitored = logs.ValidationLoss
+		}
+		lastLoss = monitored
+
+		if stopper.improved(monitored) {
+			history.BestEpoch = e
+			if nn.RestoreBestWeights {
+				bestWeights = nn.snapshot()
+			}
+		}
+		if err := notifyEpochEnd(nn.Callbacks, e, logs); err != nil {
+			return history, stopError(history, err)
+		}
+		if nn.EarlyStopping && stopper.shouldStop() {
+			history.Stopped = true
+			break
+		}
+	}
+
+	return history, nil
+}
+
+// epoch trains on every mini-batch of order once and returns the summed
+// loss and the number of correctly predicted rows. The error comes from
+// ctx or from a batch callback
+func (nn *Network) epoch(ctx context.Context, workers []*worker, inputs, targets *mat.Dense, order []int, batchSize int, optimizer Optimizer, loss Loss, accuracy accuracyFunc) (float64, int, error) {
+	if nn.Hogwild && workers != nil {
+		return nn.hogwildEpoch(ctx, workers, inputs, targets, order, batchSize, optimizer.LearningRate(), loss, accuracy)
+	}
+
+	epochLoss, epochCorrect := 0.0, 0
+	for b, start := 0, 0; start < len(order); b, start = b+1, start+batchSize {
+		if err := ctx.Err(); err != nil {
+			return epochLoss, epochCorrect, err
+		}
+		end := min(start+batchSize, len(order))
+		batch := order[start:end]
+		batchInputs, batchTargets := selectRows(inputs, batch), selectRows(targets, batch)
+		var batchLoss float64
+		var correct int
+		if workers != nil {
+			batchLoss, correct = nn.fitBatchParallel(workers, batchInputs, batchTargets, optimizer, loss, accuracy)
+		} else {
+			batchLoss, correct = nn.fitBatch(batchInputs, batchTargets, optimizer, loss, accuracy)
+		}
+		epochLoss += batchLoss * float64(len(batch))
+		epochCorrect += correct
+		if err := notifyBatchEnd(nn.Callbacks, b, batchLoss); err != nil {
+			return epochLoss, epochCorrect, err
+		}
+	}
+	return epochLoss, epochCorrect, nil
+}
+
+// evaluate returns the loss and accuracy (NaN without an accuracy
+// function) of the network on a data set in inference mode
+func (nn *Network) evaluate(inputs, targets *mat.Dense, loss Loss, accuracy accuracyFunc) (float64, float64) {
+	outputs := nn.forward(inputs, false)
+	value := loss.Loss(outputs, targets) + l2Loss(nn.parameters(), nn.Alpha)
+	if accuracy == nil {
+		return value, math.NaN()
+	}
+	rows, _ := inputs.Dims()
+	return value, float64(accuracy(outputs, targets)) / float64(rows)
+}
+
+// snapshot copies every parameter value and the non-trainable layer state
+func (nn *Network) snapshot() [][]float64 {
+	var snapshot [][]float64
+	for _, values := range nn.stateSlices() {
+		snapshot = append(snapshot, append([]float64(nil), values...))
+	}
+	return snapshot
+}
+
+// restore copies a snapshot back into the layers
+func (nn *Network) restore(snapshot [][]float64) {
+	for i, values := range nn.stateSlices() {
+		copy(values, snapshot[i])
+	}
+}
+
+// stateSlices returns the backing slices of every parameter value and of
+// the non-trainable state of layers such as batch normalization
+func (nn *Network) stateSlices() [][]float64 {
+	var slices [][]float64
+	for _, layer := range nn.Layers {
+		for _, p := range layer.Parameters() {
+			slices = append(slices, rawData(p.Value))
+		}
+		if stateful, ok := layer.(statefulLayer); ok {
+			slices = append(slices, stateful.state()...)
+		}
+	}
+	return slices
+}
+
+// forward runs a batch of samples through every layer
+func (nn *Network) forward(inputs *mat.Dense, training bool) *mat.Dense {
+	outputs := inputs
+	for _, layer := range nn.Layers {
+		outputs = layer.Forward(outputs, training)
+	}
+	return outputs
+}
+
+// fitBatch performs one optimizer step on a mini-batch and returns the
+// batch loss and the number of correctly predicted rows (0 without an
+// accuracy function) before the update
+func (nn *Network) fitBatch(inputs, targets *mat.Dense, optimizer Optimizer, loss Loss, accuracy accuracyFunc) (float64, int) {
+	outputs := nn.forward(inputs, true)
+
+	// BACKPROPAGATION: gradient of the loss w.r.t. the outputs, from output to input
+	gradient := loss.Gradient(outputs, targets)
+	for i := len(nn.Layers) - 1; i >= 0; i-- {
+		gradient = nn.Layers[i].Backward(gradient)
+	}
+
+	params := nn.parameters()
+	batchLoss := loss.Loss(outputs, targets) + l2Loss(params, nn.Alpha)
+	addL2Gradient(params, nn.Alpha)
+	optimizer.Step(params)
+
+	correct := 0
+	if accuracy != nil {
+		correct = accuracy(outputs, targets)
+	}
+	return batchLoss, correct
+}
+
+// l2Loss returns the penalty 0.5 * alpha * sum(W^2) over the regularized
+// parameters
+func l2Loss(params []*Parameter, alpha float64) float64 {
+	if alpha == 0 {
+		return 0
+	}
+	penalty := 0.0
+	for _, p := range params {
+		if p.Regularize {
+			for _, w := range rawData(p.Value) {
+				penalty += w * w
+			}
+		}
+	}
+	return 0.5 * alpha * penalty
+}
+
+// addL2Gradient adds alpha * W to the gradient of every regularized
+// parameter
+func addL2Gradient(params []*Parameter, alpha float64) {
+	if alpha == 0 {
+		return
+	}
+	for _, p := range params {
+		if p.Regularize {
+			grad := rawData(p.Grad)
+			for i, w := range rawData(p.Value) {
+				grad[i] += alpha * w
+			}
+		}
+	}
+}
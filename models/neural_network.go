@@ -1,8 +1,11 @@
 package models
 
 import (
+	"context"
 	"math"
 	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
 )
 
 // Sigmoid function
@@ -15,201 +18,147 @@ func sigmoidDerivative(y float64) float64 {
 	return y * (1 - y)
 }
 
-// MLPClassifier defines a multi-layer perceptron
+// MLPClassifier defines a multi-layer perceptron as a stack of layers.
+// The last layer outputs logits: with several output nodes they go through
+// a softmax trained with categorical cross-entropy, while a single output
+// node or Multilabel uses independent sigmoids with binary cross-entropy.
+//
+// Unlike GaussianNB and the other Naive Bayes models, MLPClassifier is not
+// generic over a label type. It learns from target rows, one-hot for a
+// softmax or one 0/1 value per output for sigmoids, and a multilabel row
+// has no single label to map back to. Predict returns the class index;
+// utils.LabelEncoder converts labels to these indices and back.
 type MLPClassifier struct {
-	InputNodes  int
-	HiddenNodes int
-	OutputNodes int
-	LearningRate float64
-
-	WeightsIH [][]float64
-	WeightsHO [][]float64
-	BiasH     [][]float64
-	BiasO     [][]float64
+	Network
+	Multilabel bool
 }
 
-// NewMLPClassifier constructor
-func NewMLPClassifier(inputNodes, hiddenNodes, outputNodes int, learningRate float64) *MLPClassifier {
-	return &MLPClassifier{
-		InputNodes:  inputNodes,
-		HiddenNodes: hiddenNodes,
-		OutputNodes: outputNodes,
-		LearningRate: learningRate,
-		WeightsIH:   randomMatrix(hiddenNodes, inputNodes),
-		WeightsHO:   randomMatrix(outputNodes, hiddenNodes),
-		BiasH:       randomMatrix(hiddenNodes, 1),
-		BiasO:       randomMatrix(outputNodes, 1),
-	}
+// NewMLPClassifier constructor. hiddenLayerSizes holds the number of
+// nodes of each hidden layer, from input to output. src seeds the weights
+// and then the shuffling, validation split and dropout, see
+// Network.Initialize; nil draws from the global math/rand source
+func NewMLPClassifier(inputNodes int, hiddenLayerSizes []int, outputNodes int, learningRate float64, src rand.Source) *MLPClassifier {
+	return &MLPClassifier{Network: newNetwork(inputNodes, hiddenLayerSizes, outputNodes, learningRate, Sigmoid{}, src)}
 }
 
-// ----------- Utility matrix operations -----------
-
-func randomMatrix(rows, cols int) [][]float64 {
-	mat := make([][]float64, rows)
-	for i := range mat {
-		mat[i] = make([]float64, cols)
-		for j := range mat[i] {
-			mat[i][j] = rand.Float64()*2 - 1
-		}
-	}
-	return mat
+// sigmoidOutputs reports whether outputs are independent sigmoids rather
+// than a softmax
+func (mlp *MLPClassifier) sigmoidOutputs() bool {
+	return mlp.Multilabel || mlp.OutputNodes == 1
 }
 
-func dot(a, b [][]float64) [][]float64 {
-	result := make([][]float64, len(a))
-	for i := range result {
-		result[i] = make([]float64, len(b[0]))
-		for j := range b[0] {
-			for k := range b {
-				result[i][j] += a[i][k] * b[k][j]
-			}
-		}
+// loss returns the loss matching the output layer
+func (mlp *MLPClassifier) loss() Loss {
+	if mlp.sigmoidOutputs() {
+		return SigmoidBinaryCrossEntropy{}
 	}
-	return result
+	return SoftmaxCrossEntropy{}
 }
 
-func add(a, b [][]float64) [][]float64 {
-	result := make([][]float64, len(a))
-	for i := range a {
-		result[i] = make([]float64, len(a[i]))
-		for j := range a[i] {
-			result[i][j] = a[i][j] + b[i][j]
-		}
-	}
-	return result
-}
+// ----------- Predict and Fit -----------
 
-func subtract(a, b [][]float64) [][]float64 {
-	result := make([][]float64, len(a))
-	for i := range a {
-		result[i] = make([]float64, len(a[i]))
-		for j := range a[i] {
-			result[i][j] = a[i][j] - b[i][j]
-		}
+// Predict returns the index of the most likely class. With a single
+// output node it returns 1 when its probability is at least 0.5
+func (mlp *MLPClassifier) Predict(input []float64) (int, error) {
+	proba, err := mlp.PredictProba(input)
+	if err != nil {
+		return 0, err
 	}
-	return result
-}
-
-func multiply(a, b [][]float64) [][]float64 {
-	result := make([][]float64, len(a))
-	for i := range a {
-		result[i] = make([]float64, len(a[i]))
-		for j := range a[i] {
-			result[i][j] = a[i][j] * b[i][j]
+	if len(proba) == 1 {
+		if proba[0] >= 0.5 {
+			return 1, nil
 		}
+		return 0, nil
 	}
-	return result
+	return argmax(proba), nil
 }
 
-func scalarMultiply(m [][]float64, scalar float64) [][]float64 {
-	result := make([][]float64, len(m))
-	for i := range m {
-		result[i] = make([]float64, len(m[i]))
-		for j := range m[i] {
-			result[i][j] = m[i][j] * scalar
-		}
+// PredictMultilabel returns, for each output node, whether its sigmoid
+// probability is at least 0.5
+func (mlp *MLPClassifier) PredictMultilabel(input []float64) ([]bool, error) {
+	proba, err := mlp.PredictProba(input)
+	if err != nil {
+		return nil, err
 	}
-	return result
-}
-
-func transpose(m [][]float64) [][]float64 {
-	result := make([][]float64, len(m[0]))
-	for i := range result {
-		result[i] = make([]float64, len(m))
-		for j := range m {
-			result[i][j] = m[j][i]
-		}
+	labels := make([]bool, len(proba))
+	for i, p := range proba {
+		labels[i] = p >= 0.5
 	}
-	return result
+	return labels, nil
 }
 
-func mapMatrix(m [][]float64, f func(float64) float64) [][]float64 {
-	result := make([][]float64, len(m))
-	for i := range m {
-		result[i] = make([]float64, len(m[i]))
-		for j := range m[i] {
-			result[i][j] = f(m[i][j])
-		}
+// PredictProba returns the class probabilities: a softmax that sums to one,
+// or independent sigmoids for a single output node or Multilabel. An input
+// of the wrong length or with an unknown embedding ID is an error
+func (mlp *MLPClassifier) PredictProba(input []float64) ([]float64, error) {
+	if err := mlp.checkInputs([][]float64{input}); err != nil {
+		return nil, err
 	}
-	return result
-}
-
-// ----------- Predict and Fit -----------
-
-func (mlp *MLPClassifier) Predict(input []float64) []float64 {
-	inputs := toColumnMatrix(input)
-
-	hidden := dot(mlp.WeightsIH, inputs)
-	hidden = add(hidden, mlp.BiasH)
-	hidden = mapMatrix(hidden, sigmoid)
-
-	outputs := dot(mlp.WeightsHO, hidden)
-	outputs = add(outputs, mlp.BiasO)
-	outputs = mapMatrix(outputs, sigmoid)
-
-	return flatten(outputs)
-}
-
-func (mlp *MLPClassifier) Fit(X [][]float64, Y [][]float64, epochs int) {
-	for e := 0; e < epochs; e++ {
-		for i := 0; i < len(X); i++ {
-			mlp.fitSingle(X[i], Y[i])
-		}
+	logits := mlp.forward(mat.NewDense(1, len(input), append([]float64(nil), input...)), false)
+	return mlp.probabilities(logits.RawRowView(0)), nil
+}
+
+// PredictBatch returns the class probabilities of every row of X as an
+// n x k matrix, computed with one forward pass over the whole batch
+// instead of one per sample. With Workers > 1 the rows are split across
+// that many goroutines
+func (mlp *MLPClassifier) PredictBatch(X [][]float64) (*mat.Dense, error) {
+	proba, err := mlp.predictBatch(X)
+	if err != nil {
+		return nil, err
 	}
+	rows, _ := proba.Dims()
+	for i := 0; i < rows; i++ {
+		row := proba.RawRowView(i)
+		copy(row, mlp.probabilities(row))
+	}
+	return proba, nil
 }
 
-func (mlp *MLPClassifier) fitSingle(input, target []float64) {
-	inputs := toColumnMatrix(input)
-	targets := toColumnMatrix(target)
-
-	// FORWARD
-	hidden := dot(mlp.WeightsIH, inputs)
-	hidden = add(hidden, mlp.BiasH)
-	hidden = mapMatrix(hidden, sigmoid)
-
-	outputs := dot(mlp.WeightsHO, hidden)
-	outputs = add(outputs, mlp.BiasO)
-	outputs = mapMatrix(outputs, sigmoid)
-
-	// BACKPROPAGATION
-	outputErrors := subtract(targets, outputs)
-	gradients := mapMatrix(outputs, sigmoidDerivative)
-	gradients = multiply(gradients, outputErrors)
-	gradients = scalarMultiply(gradients, mlp.LearningRate)
-
-	hiddenT := transpose(hidden)
-	weightsHODeltas := dot(gradients, hiddenT)
-
-	mlp.WeightsHO = add(mlp.WeightsHO, weightsHODeltas)
-	mlp.BiasO = add(mlp.BiasO, gradients)
-
-	whoT := transpose(mlp.WeightsHO)
-	hiddenErrors := dot(whoT, outputErrors)
-
-	hiddenGradient := mapMatrix(hidden, sigmoidDerivative)
-	hiddenGradient = multiply(hiddenGradient, hiddenErrors)
-	hiddenGradient = scalarMultiply(hiddenGradient, mlp.LearningRate)
-
-	inputsT := transpose(inputs)
-	weightsIHDeltas := dot(hiddenGradient, inputsT)
+// Fit trains the network for the given number of epochs using mini-batch
+// gradient descent and returns the per-epoch History
+func (mlp *MLPClassifier) Fit(X [][]float64, Y [][]float64, epochs int) (*History, error) {
+	return mlp.FitContext(context.Background(), X, Y, epochs)
+}
 
-	mlp.WeightsIH = add(mlp.WeightsIH, weightsIHDeltas)
-	mlp.BiasH = add(mlp.BiasH, hiddenGradient)
+// FitContext is Fit with cancellation: training stops between mini-batches
+// once ctx is done, and the History so far is returned with ctx.Err(). The
+// Callbacks are notified as training progresses; a callback returning
+// ErrStopTraining ends training without an error, any other error aborts it
+func (mlp *MLPClassifier) FitContext(ctx context.Context, X [][]float64, Y [][]float64, epochs int) (*History, error) {
+	return mlp.fit(ctx, X, Y, epochs, mlp.loss(), mlp.correct)
 }
 
-// Helper functions
-func toColumnMatrix(arr []float64) [][]float64 {
-	result := make([][]float64, len(arr))
-	for i := range arr {
-		result[i] = []float64{arr[i]}
-	}
-	return result
+// correct counts the rows whose predicted labels all match the targets
+func (mlp *MLPClassifier) correct(outputs, targets *mat.Dense) int {
+	return countCorrect(outputs, targets, mlp.sigmoidOutputs())
 }
 
-func flatten(matrix [][]float64) []float64 {
-	result := make([]float64, len(matrix))
-	for i := range matrix {
-		result[i] = matrix[i][0]
+// countCorrect counts the rows whose predicted labels all match the
+// targets: the argmax for softmax outputs, or every logit thresholded at
+// 0 for sigmoid outputs
+func countCorrect(outputs, targets *mat.Dense, sigmoidOutputs bool) int {
+	rows, cols := outputs.Dims()
+	count := 0
+	for i := 0; i < rows; i++ {
+		logits, target := outputs.RawRowView(i), targets.RawRowView(i)
+		if !sigmoidOutputs {
+			if argmax(logits) == argmax(target) {
+				count++
+			}
+			continue
+		}
+		match := true
+		for j := 0; j < cols; j++ {
+			// A logit of 0 is a probability of 0.5
+			if (logits[j] >= 0) != (target[j] >= 0.5) {
+				match = false
+				break
+			}
+		}
+		if match {
+			count++
+		}
 	}
-	return result
+	return count
 }
@@ -0,0 +1,242 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// xorData returns the four XOR samples with one-hot targets
+func xorData() ([][]float64, [][]float64) {
+	X := [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	Y := [][]float64{{1, 0}, {0, 1}, {0, 1}, {1, 0}}
+	return X, Y
+}
+
+func TestMLPClassifierLearnsXOR(t *testing.T) {
+	X, Y := xorData()
+	mlp := NewMLPClassifier(2, []int{8}, 2, 0.5, rand.NewSource(1))
+	if err := mlp.SetActivation(0, "tanh"); err != nil {
+		t.Fatal(err)
+	}
+	mlp.BatchSize = 4
+	if _, err := mlp.Fit(X, Y, 2000); err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range X {
+		class, err := mlp.Predict(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Y[i][class] != 1 {
+			t.Errorf("Predict(%v) = %d, expected the class of %v", row, class, Y[i])
+		}
+	}
+}
+
+func TestMLPClassifierPredictRejectsWrongWidth(t *testing.T) {
+	mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, nil)
+	if _, err := mlp.Predict([]float64{1, 2, 3}); err == nil {
+		t.Error("expected an error for a 3-feature input")
+	}
+}
+
+func TestPredictBatchMatchesPredictProba(t *testing.T) {
+	mlp := trainBlobs(t, 1, false, 5)
+	X, _ := blobs(13, 9)
+	want := make([][]float64, len(X))
+	for i, row := range X {
+		proba, err := mlp.PredictProba(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[i] = proba
+	}
+
+	for _, workers := range []int{1, 2, len(X) + 1} {
+		mlp.Workers = workers
+		proba, err := mlp.PredictBatch(X)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range want {
+			for j, p := range proba.RawRowView(i) {
+				if math.Abs(p-want[i][j]) > 1e-12 {
+					t.Errorf("%d workers: row %d class %d: %v, expected %v", workers, i, j, p, want[i][j])
+				}
+			}
+		}
+	}
+}
+
+// fixedOutputs returns a classifier whose outputs ignore the input: the
+// output layer has zero weights and the given logits as biases
+func fixedOutputs(logits []float64) *MLPClassifier {
+	mlp := NewMLPClassifier(2, []int{3}, len(logits), 0.1, nil)
+	output := mlp.Layers[len(mlp.Layers)-1].(*DenseLayer)
+	output.Weights.Value.Zero()
+	output.Bias.Value.SetRow(0, logits)
+	return mlp
+}
+
+func TestPredictProbaSoftmax(t *testing.T) {
+	mlp := fixedOutputs([]float64{1, 2, 3})
+	proba, err := mlp.PredictProba([]float64{0.3, -0.7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	norm := math.Exp(1) + math.Exp(2) + math.Exp(3)
+	sum := 0.0
+	for i, p := range proba {
+		if want := math.Exp(float64(i+1)) / norm; math.Abs(p-want) > 1e-12 {
+			t.Errorf("class %d: probability %v, expected %v", i, p, want)
+		}
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-12 {
+		t.Errorf("probabilities sum to %v", sum)
+	}
+	if class, err := mlp.Predict([]float64{0.3, -0.7}); err != nil || class != 2 {
+		t.Errorf("Predict returned %v, %v, expected 2", class, err)
+	}
+
+	// Softmax rows of a trained network sum to one as well
+	X, Y := xorData()
+	trained := NewMLPClassifier(2, []int{4}, 2, 0.5, rand.NewSource(2))
+	if _, err := trained.Fit(X, Y, 20); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range X {
+		proba, err := trained.PredictProba(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(proba[0]+proba[1]-1) > 1e-12 {
+			t.Errorf("probabilities %v do not sum to one", proba)
+		}
+	}
+}
+
+func TestPredictMultilabel(t *testing.T) {
+	logits := []float64{2, -2, 0, 5}
+	mlp := fixedOutputs(logits)
+	mlp.Multilabel = true
+	proba, err := mlp.PredictProba([]float64{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each output is an independent sigmoid, so they need not sum to one
+	for i, z := range logits {
+		if want := 1 / (1 + math.Exp(-z)); math.Abs(proba[i]-want) > 1e-12 {
+			t.Errorf("label %d: probability %v, expected %v", i, proba[i], want)
+		}
+	}
+
+	labels, err := mlp.PredictMultilabel([]float64{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A probability of exactly 0.5 counts as a positive label
+	want := []bool{true, false, true, true}
+	for i := range want {
+		if labels[i] != want[i] {
+			t.Errorf("got labels %v, expected %v", labels, want)
+			break
+		}
+	}
+}
+
+func TestPredictSingleOutputIsBinary(t *testing.T) {
+	for _, tt := range []struct {
+		logit float64
+		class int
+	}{{-1, 0}, {0, 1}, {1, 1}} {
+		mlp := fixedOutputs([]float64{tt.logit})
+		if class, err := mlp.Predict([]float64{0, 0}); err != nil || class != tt.class {
+			t.Errorf("logit %v: class %d, %v, expected %d", tt.logit, class, err, tt.class)
+		}
+	}
+}
+
+// benchmarkFit trains on 1024 samples of 20 features, one epoch per
+// iteration, with the given batch size
+func benchmarkFit(b *testing.B, batchSize int) {
+	rng := rand.New(rand.NewSource(1))
+	X := make([][]float64, 1024)
+	Y := make([][]float64, len(X))
+	for i := range X {
+		X[i] = make([]float64, 20)
+		for j := range X[i] {
+			X[i][j] = rng.NormFloat64()
+		}
+		Y[i] = make([]float64, 3)
+		Y[i][rng.Intn(3)] = 1
+	}
+	mlp := NewMLPClassifier(20, []int{64, 32}, 3, 0.01, rand.NewSource(1))
+	mlp.BatchSize = batchSize
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mlp.Fit(X, Y, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMLPFitPerSample updates the weights after every sample, like
+// the original training loop
+func BenchmarkMLPFitPerSample(b *testing.B) { benchmarkFit(b, 1) }
+
+// BenchmarkMLPFitMiniBatch runs each forward and backward pass on 32
+// samples at once
+func BenchmarkMLPFitMiniBatch(b *testing.B) { benchmarkFit(b, 32) }
+
+func TestFitRejectsValidationFractionOutOfRange(t *testing.T) {
+	X, Y := xorData()
+	for _, fraction := range []float64{-0.1, 1, 1.5} {
+		mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, nil)
+		mlp.ValidationFraction = fraction
+		if _, err := mlp.Fit(X, Y, 1); err == nil {
+			t.Errorf("ValidationFraction %v: expected an error", fraction)
+		}
+	}
+}
+
+func TestFitValidationSplit(t *testing.T) {
+	X, Y := xorData()
+	mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, rand.NewSource(1))
+	mlp.ValidationFraction = 0.5
+	history, err := mlp.Fit(X, Y, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.ValidationLoss) != 3 {
+		t.Errorf("got %d validation losses, expected 3", len(history.ValidationLoss))
+	}
+}
+
+// lossSchedule records the losses a schedule receives
+type lossSchedule struct{ losses []float64 }
+
+func (s *lossSchedule) LearningRate(epoch int, initialRate, lastLoss float64) float64 {
+	s.losses = append(s.losses, lastLoss)
+	return initialRate
+}
+
+func TestScheduleMonitorsValidationLoss(t *testing.T) {
+	X, Y := xorData()
+	mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, rand.NewSource(1))
+	mlp.ValidationFraction = 0.5
+	schedule := &lossSchedule{}
+	mlp.Schedule = schedule
+	history, err := mlp.Fit(X, Y, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for e := 1; e < 3; e++ {
+		if schedule.losses[e] != history.ValidationLoss[e-1] {
+			t.Errorf("epoch %d: schedule got loss %v, expected the validation loss %v",
+				e, schedule.losses[e], history.ValidationLoss[e-1])
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package models
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Parameter is a trainable matrix together with the gradient of the loss
+// computed for it by the last backward pass. Regularize marks weights that
+// the L2 penalty applies to, as opposed to biases and normalization
+// parameters.
+type Parameter struct {
+	Value      *mat.Dense
+	Grad       *mat.Dense
+	Regularize bool
+
+	// SparseRows, when not nil, lists the only rows of Grad holding
+	// gradients, such as the embedding rows used by a batch. Optimizers
+	// then update only those rows and leave the state of the others as is.
+	SparseRows []int
+}
+
+// spans returns the [start, end) ranges of the backing data of Grad to
+// update: all of it for dense parameters, the SparseRows otherwise
+func (p *Parameter) spans() [][2]int {
+	if p.SparseRows == nil {
+		return [][2]int{{0, len(rawData(p.Grad))}}
+	}
+	_, cols := p.Grad.Dims()
+	spans := make([][2]int, len(p.SparseRows))
+	for i, row := range p.SparseRows {
+		spans[i] = [2]int{row * cols, (row + 1) * cols}
+	}
+	return spans
+}
+
+// newParameter wraps a matrix with a zero gradient of the same shape
+func newParameter(value *mat.Dense) *Parameter {
+	rows, cols := value.Dims()
+	return &Parameter{Value: value, Grad: mat.NewDense(rows, cols, nil)}
+}
+
+// Optimizer updates parameters from their gradients. Implementations keep
+// per-parameter state (velocities, moment estimates, ...) keyed by the
+// *Parameter, so the same parameters must be passed on every Step.
+type Optimizer interface {
+	Step(params []*Parameter)
+	LearningRate() float64
+	SetLearningRate(rate float64)
+}
+
+// rawData returns the backing slice of a matrix created with mat.NewDense
+func rawData(m *mat.Dense) []float64 {
+	return m.RawMatrix().Data
+}
+
+// optimizerState lazily allocates one zero slice per parameter
+type optimizerState map[*Parameter][]float64
+
+func (s *optimizerState) get(p *Parameter) []float64 {
+	if *s == nil {
+		*s = make(optimizerState)
+	}
+	state, ok := (*s)[p]
+	if !ok {
+		state = make([]float64, len(rawData(p.Value)))
+		(*s)[p] = state
+	}
+	return state
+}
+
+// SGD is stochastic gradient descent with optional classical or Nesterov
+// momentum
+type SGD struct {
+	Rate     float64
+	Momentum float64
+	Nesterov bool
+
+	velocity optimizerState
+}
+
+// NewSGD creates an SGD optimizer; momentum 0 gives plain gradient descent
+func NewSGD(rate, momentum float64, nesterov bool) *SGD {
+	return &SGD{Rate: rate, Momentum: momentum, Nesterov: nesterov}
+}
+
+func (o *SGD) LearningRate() float64        { return o.Rate }
+func (o *SGD) SetLearningRate(rate float64) { o.Rate = rate }
+
+func (o *SGD) Step(params []*Parameter) {
+	for _, p := range params {
+		value, grad := rawData(p.Value), rawData(p.Grad)
+		if o.Momentum == 0 {
+			for _, span := range p.spans() {
+				for i := span[0]; i < span[1]; i++ {
+					value[i] -= o.Rate * grad[i]
+				}
+			}
+			continue
+		}
+		velocity := o.velocity.get(p)
+		for _, span := range p.spans() {
+			for i := span[0]; i < span[1]; i++ {
+				g := grad[i]
+				velocity[i] = o.Momentum*velocity[i] + g
+				if o.Nesterov {
+					g += o.Momentum * velocity[i]
+				} else {
+					g = velocity[i]
+				}
+				value[i] -= o.Rate * g
+			}
+		}
+	}
+}
+
+// Adagrad scales each step by the inverse square root of the sum of all
+// past squared gradients
+type Adagrad struct {
+	Rate    float64
+	Epsilon float64
+
+	sumSquares optimizerState
+}
+
+// NewAdagrad creates an Adagrad optimizer with epsilon 1e-8
+func NewAdagrad(rate float64) *Adagrad {
+	return &Adagrad{Rate: rate, Epsilon: 1e-8}
+}
+
+func (o *Adagrad) LearningRate() float64        { return o.Rate }
+func (o *Adagrad) SetLearningRate(rate float64) { o.Rate = rate }
+
+func (o *Adagrad) Step(params []*Parameter) {
+	for _, p := range params {
+		value, grad := rawData(p.Value), rawData(p.Grad)
+		sumSquares := o.sumSquares.get(p)
+		for _, span := range p.spans() {
+			for i := span[0]; i < span[1]; i++ {
+				g := grad[i]
+				sumSquares[i] += g * g
+				value[i] -= o.Rate * g / (math.Sqrt(sumSquares[i]) + o.Epsilon)
+			}
+		}
+	}
+}
+
+// RMSProp scales each step by a moving average of squared gradients
+type RMSProp struct {
+	Rate    float64
+	Rho     float64 // Decay of the squared-gradient average
+	Epsilon float64
+
+	meanSquares optimizerState
+}
+
+// NewRMSProp creates an RMSProp optimizer with rho 0.9 and epsilon 1e-8
+func NewRMSProp(rate float64) *RMSProp {
+	return &RMSProp{Rate: rate, Rho: 0.9, Epsilon: 1e-8}
+}
+
+func (o *RMSProp) LearningRate() float64        { return o.Rate }
+func (o *RMSProp) SetLearningRate(rate float64) { o.Rate = rate }
+
+func (o *RMSProp) Step(params []*Parameter) {
+	for _, p := range params {
+		value, grad := rawData(p.Value), rawData(p.Grad)
+		meanSquares := o.meanSquares.get(p)
+		for _, span := range p.spans() {
+			for i := span[0]; i < span[1]; i++ {
+				g := grad[i]
+				meanSquares[i] = o.Rho*meanSquares[i] + (1-o.Rho)*g*g
+				value[i] -= o.Rate * g / (math.Sqrt(meanSquares[i]) + o.Epsilon)
+			}
+		}
+	}
+}
+
+// Adam keeps bias-corrected moving averages of the gradient and of its
+// square. A non-zero WeightDecay turns it into AdamW, which shrinks the
+// weights directly instead of adding an L2 term to the gradient. Like the
+// L2 penalty, the decay only applies to parameters marked Regularize, not
+// to biases and normalization parameters.
+type Adam struct {
+	Rate        float64
+	Beta1       float64
+	Beta2       float64
+	Epsilon     float64
+	WeightDecay float64
+
+	firstMoment  optimizerState
+	secondMoment optimizerState
+	steps        map[*Parameter]int
+}
+
+// NewAdam creates an Adam optimizer with beta1 0.9, beta2 0.999 and
+// epsilon 1e-8
+func NewAdam(rate float64) *Adam {
+	return &Adam{Rate: rate, Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8}
+}
+
+// NewAdamW creates an Adam optimizer with decoupled weight decay
+func NewAdamW(rate, weightDecay float64) *Adam {
+	adam := NewAdam(rate)
+	adam.WeightDecay = weightDecay
+	return adam
+}
+
+func (o *Adam) LearningRate() float64        { return o.Rate }
+func (o *Adam) SetLearningRate(rate float64) { o.Rate = rate }
+
+func (o *Adam) Step(params []*Parameter) {
+	if o.steps == nil {
+		o.steps = make(map[*Parameter]int)
+	}
+	for _, p := range params {
+		value, grad := rawData(p.Value), rawData(p.Grad)
+		m, v := o.firstMoment.get(p), o.secondMoment.get(p)
+		o.steps[p]++
+		t := float64(o.steps[p])
+		correction1 := 1 - math.Pow(o.Beta1, t)
+		correction2 := 1 - math.Pow(o.Beta2, t)
+		decay := o.WeightDecay
+		if !p.Regularize {
+			decay = 0
+		}
+
+		for _, span := range p.spans() {
+			for i := span[0]; i < span[1]; i++ {
+				g := grad[i]
+				m[i] = o.Beta1*m[i] + (1-o.Beta1)*g
+				v[i] = o.Beta2*v[i] + (1-o.Beta2)*g*g
+				mHat := m[i] / correction1
+				vHat := v[i] / correction2
+				value[i] -= o.Rate * (mHat/(math.Sqrt(vHat)+o.Epsilon) + decay*value[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// stepParameter returns a parameter holding [1 -2] with gradient
+// [0.5 -0.25], the values every optimizer test starts from
+func stepParameter() *Parameter {
+	p := newParameter(mat.NewDense(1, 2, []float64{1, -2}))
+	p.Grad.SetRow(0, []float64{0.5, -0.25})
+	return p
+}
+
+func TestOptimizerSteps(t *testing.T) {
+	g := []float64{0.5, -0.25}
+	// expected computes the value after the steps from the update u(g) that
+	// each step subtracts
+	expected := func(update func(g float64) float64) []float64 {
+		return []float64{1 - update(g[0]), -2 - update(g[1])}
+	}
+	tests := []struct {
+		name      string
+		optimizer Optimizer
+		steps     int
+		want      []float64
+	}{
+		{"SGD", NewSGD(0.1, 0, false), 1,
+			expected(func(g float64) float64 { return 0.1 * g })},
+		// Velocities g and 0.9g+g
+		{"Momentum", NewSGD(0.1, 0.9, false), 2,
+			expected(func(g float64) float64 { return 0.1*g + 0.1*1.9*g })},
+		// Look-ahead gradients g+0.9g and g+0.9(1.9g)
+		{"Nesterov", NewSGD(0.1, 0.9, true), 2,
+			expected(func(g float64) float64 { return 0.1*1.9*g + 0.1*2.71*g })},
+		{"Adagrad", NewAdagrad(0.1), 1,
+			expected(func(g float64) float64 { return 0.1 * g / (math.Sqrt(g*g) + 1e-8) })},
+		{"RMSProp", NewRMSProp(0.1), 1,
+			expected(func(g float64) float64 { return 0.1 * g / (math.Sqrt(0.1*g*g) + 1e-8) })},
+		// The bias correction makes the first moments g and g²
+		{"Adam", NewAdam(0.1), 1,
+			expected(func(g float64) float64 { return 0.1 * g / (math.Sqrt(g*g) + 1e-8) })},
+	}
+	for _, tt := range tests {
+		p := stepParameter()
+		for step := 0; step < tt.steps; step++ {
+			tt.optimizer.Step([]*Parameter{p})
+		}
+		for i, got := range p.Value.RawRowView(0) {
+			if math.Abs(got-tt.want[i]) > 1e-12 {
+				t.Errorf("%s: element %d is %v, expected %v", tt.name, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+// recordingSchedule wraps a schedule and records the rate of every epoch
+type recordingSchedule struct {
+	LearningRateSchedule
+	rates []float64
+}
+
+func (s *recordingSchedule) LearningRate(epoch int, initialRate, lastLoss float64) float64 {
+	rate := s.LearningRateSchedule.LearningRate(epoch, initialRate, lastLoss)
+	s.rates = append(s.rates, rate)
+	return rate
+}
+
+func TestScheduleRestartsFromBaseRate(t *testing.T) {
+	X, Y := xorData()
+	mlp := NewMLPClassifier(2, []int{3}, 2, 0.1, nil)
+	mlp.Optimizer = NewSGD(0.2, 0, false)
+	schedule := &recordingSchedule{LearningRateSchedule: ExponentialDecay{Gamma: 0.5}}
+	mlp.Schedule = schedule
+	for run := 0; run < 2; run++ {
+		if _, err := mlp.Fit(X, Y, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each run decays from the optimizer's own rate
+	want := []float64{0.2, 0.1, 0.2, 0.1}
+	for i := range want {
+		if schedule.rates[i] != want[i] {
+			t.Fatalf("got rates %v, expected %v", schedule.rates, want)
+		}
+	}
+	if rate := mlp.Optimizer.LearningRate(); rate != 0.2 {
+		t.Errorf("optimizer left at rate %v, expected 0.2", rate)
+	}
+}
+
+func TestOptimizerSparseRows(t *testing.T) {
+	p := newParameter(mat.NewDense(2, 1, []float64{1, 1}))
+	p.Grad.Set(0, 0, 1)
+	p.Grad.Set(1, 0, 1)
+	p.SparseRows = []int{1}
+	NewSGD(0.5, 0, false).Step([]*Parameter{p})
+	if got := rawData(p.Value); got[0] != 1 || got[1] != 0.5 {
+		t.Errorf("got %v, expected only row 1 to move to 0.5", got)
+	}
+}
+
+func TestAdamWDecaysOnlyRegularizedParameters(t *testing.T) {
+	weights := newParameter(mat.NewDense(1, 2, []float64{1, -2}))
+	weights.Regularize = true
+	bias := newParameter(mat.NewDense(1, 2, []float64{0.5, -0.5}))
+
+	adam := NewAdamW(0.1, 0.01)
+	for step := 0; step < 5; step++ {
+		adam.Step([]*Parameter{weights, bias})
+	}
+
+	// With zero gradients only the decay moves a value
+	if got := bias.Value.RawRowView(0); got[0] != 0.5 || got[1] != -0.5 {
+		t.Errorf("bias changed to %v", got)
+	}
+	if got := weights.Value.RawRowView(0); got[0] >= 1 || got[1] <= -2 {
+		t.Errorf("weights %v were not decayed", got)
+	}
+}
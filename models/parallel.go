@@ -0,0 +1,406 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// replicaLayer is implemented by layers that can be copied for parallel
+// training. A replica shares the parameter values of the original layer
+// but has its own gradients and forward state, so replicas can run
+// forward and backward passes on different goroutines.
+type replicaLayer interface {
+	replica() Layer
+}
+
+// replica returns a parameter sharing the value of p with its own zero
+// gradient
+func (p *Parameter) replica() *Parameter {
+	rows, cols := p.Grad.Dims()
+	r := &Parameter{Value: p.Value, Grad: mat.NewDense(rows, cols, nil), Regularize: p.Regularize}
+	if p.SparseRows != nil {
+		r.SparseRows = []int{}
+	}
+	return r
+}
+
+func (l *DenseLayer) replica() Layer {
+	r := *l
+	r.Weights, r.Bias, r.tape = l.Weights.replica(), l.Bias.replica(), nil
+	return &r
+}
+
+func (l *ActivationLayer) replica() Layer {
+	return &ActivationLayer{Activation: l.Activation}
+}
+
+func (l *DropoutLayer) replica() Layer {
+	return &DropoutLayer{Rate: l.Rate}
+}
+
+func (l *GaussianNoiseLayer) replica() Layer {
+	return &GaussianNoiseLayer{StdDev: l.StdDev}
+}
+
+// The running statistics of a replica are private copies: only the
+// original layer, trained by the first worker, updates the shared ones
+func (l *BatchNormLayer) replica() Layer {
+	r := *l
+	r.Gamma, r.Beta, r.tape = l.Gamma.replica(), l.Beta.replica(), nil
+	r.RunningMean = append([]float64(nil), l.RunningMean...)
+	r.RunningVar = append([]float64(nil), l.RunningVar...)
+	return &r
+}
+
+func (l *Conv2D) replica() Layer {
+	r := *l
+	r.Kernel, r.Bias, r.tape = l.Kernel.replica(), l.Bias.replica(), nil
+	return &r
+}
+
+func (l *MaxPool2D) replica() Layer { return &MaxPool2D{window: l.window} }
+
+func (l *AvgPool2D) replica() Layer { return &AvgPool2D{window: l.window} }
+
+func (l *Flatten) replica() Layer { return l }
+
+// replica copies the shared recurrent state for the layers embedding it
+func (r recurrent) replica() recurrent {
+	r.Weights, r.Recurrent, r.Bias, r.tape = r.Weights.replica(), r.Recurrent.replica(), r.Bias.replica(), nil
+	return r
+}
+
+func (l *SimpleRNN) replica() Layer {
+	return &SimpleRNN{recurrent: l.recurrent.replica(), Activation: l.Activation}
+}
+
+func (l *GRU) replica() Layer { return &GRU{l.recurrent.replica()} }
+
+func (l *LSTM) replica() Layer { return &LSTM{l.recurrent.replica()} }
+
+func (l *Embedding) replica() Layer {
+	r := *l
+	r.Table, r.ids = l.Table.replica(), nil
+	return &r
+}
+
+// worker is the layer stack trained by one goroutine
+type worker struct {
+	layers []Layer
+	params []*Parameter
+}
+
+// newWorkers returns nil for single-goroutine training, or one worker per
+// goroutine: the first trains the layers of the network and the others
+// replicas of them. Every replica draws its random numbers from its own
+// generator, seeded from the network generator
+func (nn *Network) newWorkers() ([]*worker, error) {
+	if nn.Workers <= 1 {
+		return nil, nil
+	}
+	workers := []*worker{{layers: nn.Layers, params: nn.parameters()}}
+	for len(workers) < nn.Workers {
+		layers, err := nn.replicateLayers()
+		if err != nil {
+			return nil, err
+		}
+		w := &worker{layers: layers}
+		rng := rand.New(rand.NewSource(nn.int63()))
+		for _, layer := range layers {
+			if random, ok := layer.(randomLayer); ok {
+				random.setRand(rng)
+			}
+			w.params = append(w.params, layer.Parameters()...)
+		}
+		workers = append(workers, w)
+	}
+	return workers, nil
+}
+
+// replicateLayers returns replicas of every layer
+func (nn *Network) replicateLayers() ([]Layer, error) {
+	layers := make([]Layer, len(nn.Layers))
+	for i, layer := range nn.Layers {
+		replicable, ok := layer.(replicaLayer)
+		if !ok {
+			return nil, fmt.Errorf("layer %d (%T) does not support parallel execution", i, layer)
+		}
+		layers[i] = replicable.replica()
+	}
+	return layers, nil
+}
+
+// int63 draws a seed from the network generator
+func (nn *Network) int63() int64 {
+	if nn.rng == nil {
+		return rand.Int63()
+	}
+	return nn.rng.Int63()
+}
+
+// gradients runs a forward and a backward pass of the worker on a batch,
+// leaving the gradients in its parameters, and returns the batch loss and
+// the number of correctly predicted rows
+func (w *worker) gradients(inputs, targets *mat.Dense, loss Loss, accuracy accuracyFunc) (float64, int) {
+	outputs := inputs
+	for _, layer := range w.layers {
+		outputs = layer.Forward(outputs, true)
+	}
+	gradient := loss.Gradient(outputs, targets)
+	for i := len(w.layers) - 1; i >= 0; i-- {
+		gradient = w.layers[i].Backward(gradient)
+	}
+	correct := 0
+	if accuracy != nil {
+		correct = accuracy(outputs, targets)
+	}
+	return loss.Loss(outputs, targets), correct
+}
+
+// shard is the part of a mini-batch handled by one worker
+type shard struct {
+	inputs, targets *mat.Dense
+	rows            int
+	loss            float64
+	correct         int
+}
+
+// fitBatchParallel splits a mini-batch into contiguous shards, computes
+// their gradients concurrently and reduces them in worker order, so the
+// result does not depend on scheduling. It then performs one optimizer
+// step like fitBatch.
+func (nn *Network) fitBatchParallel(workers []*worker, inputs, targets *mat.Dense, optimizer Optimizer, loss Loss, accuracy accuracyFunc) (float64, int) {
+	rows, _ := inputs.Dims()
+	n := min(len(workers), rows)
+	shards := make([]shard, n)
+	var wg sync.WaitGroup
+	for k := range shards {
+		from, to := k*rows/n, (k+1)*rows/n
+		indices := make([]int, to-from)
+		for i := range indices {
+			indices[i] = from + i
+		}
+		shards[k] = shard{inputs: selectRows(inputs, indices), targets: selectRows(targets, indices), rows: to - from}
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			s := &shards[k]
+			s.loss, s.correct = workers[k].gradients(s.inputs, s.targets, loss, accuracy)
+		}(k)
+	}
+	wg.Wait()
+
+	// The losses are means over each shard, so the batch gradient is the
+	// average of the shard gradients weighted by their sizes
+	params := workers[0].params
+	batchLoss, correct := 0.0, 0
+	for k, s := range shards {
+		weight := float64(s.rows) / float64(rows)
+		batchLoss += weight * s.loss
+		correct += s.correct
+		for i, p := range params {
+			reduceGradient(p, workers[k].params[i], weight, k == 0)
+		}
+	}
+
+	batchLoss += l2Loss(params, nn.Alpha)
+	addL2Gradient(params, nn.Alpha)
+	optimizer.Step(params)
+	return batchLoss, correct
+}
+
+// reduceGradient adds weight times the gradient of a worker parameter into
+// the gradient of the shared one. The first worker trains the shared
+// parameter itself, so its gradient is only scaled.
+func reduceGradient(shared, local *Parameter, weight float64, first bool) {
+	grad, localGrad := rawData(shared.Grad), rawData(local.Grad)
+	if first {
+		for _, span := range shared.spans() {
+			for i := span[0]; i < span[1]; i++ {
+				grad[i] *= weight
+			}
+		}
+		return
+	}
+	if shared.SparseRows != nil {
+		for _, row := range local.SparseRows {
+			if !containsInt(shared.SparseRows, row) {
+				shared.SparseRows = append(shared.SparseRows, row)
+			}
+		}
+	}
+	for _, span := range local.spans() {
+		for i := span[0]; i < span[1]; i++ {
+			grad[i] += weight * localGrad[i]
+		}
+	}
+}
+
+// containsInt reports whether values holds v
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// hogwildEpoch trains one epoch Hogwild-style: every worker takes the next
+// mini-batch as soon as it is free and applies a plain SGD step at the
+// current learning rate straight to the shared parameters, without
+// waiting for the other workers to finish their batches. Each parameter
+// has its own lock, held shared for a whole forward and backward pass and
+// exclusively while a step writes it. Passes of different workers run
+// concurrently, but a step waits until no pass is reading its parameter
+// and new passes wait for the step, so the steps are not lock-free and
+// the speedup is limited to the gradient computation. Steps of different
+// workers interleave in scheduling order and never tear a value another
+// worker is reading. Batch callbacks run on the calling goroutine in
+// completion order.
+func (nn *Network) hogwildEpoch(ctx context.Context, workers []*worker, inputs, targets *mat.Dense, order []int, batchSize int, rate float64, loss Loss, accuracy accuracyFunc) (float64, int, error) {
+	type result struct {
+		rows    int
+		loss    float64
+		correct int
+	}
+	// Replicas list their parameters in the order of the shared ones, so
+	// the lock of a parameter has the same index for every worker
+	locks := make([]sync.RWMutex, len(workers[0].params))
+	batches := make(chan []int)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			for batch := range batches {
+				// Locks are always taken in index order, so a worker
+				// waiting for a read lock never holds what a writer needs
+				for i := range locks {
+					locks[i].RLock()
+				}
+				batchLoss, correct := w.gradients(selectRows(inputs, batch), selectRows(targets, batch), loss, accuracy)
+				batchLoss += l2Loss(w.params, nn.Alpha)
+				for i := range locks {
+					locks[i].RUnlock()
+				}
+
+				for i, p := range w.params {
+					locks[i].Lock()
+					addL2Gradient([]*Parameter{p}, nn.Alpha)
+					value, grad := rawData(p.Value), rawData(p.Grad)
+					for _, span := range p.spans() {
+						for j := span[0]; j < span[1]; j++ {
+							value[j] -= rate * grad[j]
+						}
+					}
+					locks[i].Unlock()
+				}
+				results <- result{rows: len(batch), loss: batchLoss, correct: correct}
+			}
+		}(w)
+	}
+
+	// Feed the batches while collecting the results, stopping the feed on
+	// the first error
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	var err error
+	next, total := 0, (len(order)+batchSize-1)/batchSize
+	feed := batches
+	epochLoss, epochCorrect := 0.0, 0
+	for b := 0; b < total; {
+		var batch []int
+		if feed != nil && next < total {
+			batch = order[next*batchSize : min((next+1)*batchSize, len(order))]
+		}
+		select {
+		case feed <- batch:
+			next++
+			if next == total {
+				close(batches)
+				feed = nil
+			}
+		case r, ok := <-results:
+			if !ok {
+				return epochLoss, epochCorrect, err
+			}
+			epochLoss += r.loss * float64(r.rows)
+			epochCorrect += r.correct
+			if err == nil {
+				err = notifyBatchEnd(nn.Callbacks, b, r.loss)
+			}
+			b++
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil && feed != nil {
+			close(batches)
+			feed = nil
+			total = next
+		}
+	}
+	if feed != nil {
+		close(batches)
+	}
+	for range results {
+	}
+	return epochLoss, epochCorrect, err
+}
+
+// predictBatch runs the rows of X through the network in inference mode
+// and returns the outputs, one row per sample. With Workers > 1 the rows
+// are split into contiguous shards run concurrently on replicas of the
+// layers.
+func (nn *Network) predictBatch(X [][]float64) (*mat.Dense, error) {
+	if err := nn.checkInputs(X); err != nil {
+		return nil, err
+	}
+	inputs := rowsToDense(X)
+	n := min(max(nn.Workers, 1), len(X))
+	if n == 1 {
+		return nn.forward(inputs, false), nil
+	}
+
+	stacks := [][]Layer{nn.Layers}
+	for len(stacks) < n {
+		layers, err := nn.replicateLayers()
+		if err != nil {
+			return nil, err
+		}
+		stacks = append(stacks, layers)
+	}
+	rows := len(X)
+	outputs := make([]*mat.Dense, n)
+	var wg sync.WaitGroup
+	for k := range stacks {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			shard := inputs.Slice(k*rows/n, (k+1)*rows/n, 0, nn.InputNodes).(*mat.Dense)
+			output := mat.DenseCopyOf(shard)
+			for _, layer := range stacks[k] {
+				output = layer.Forward(output, false)
+			}
+			outputs[k] = output
+		}(k)
+	}
+	wg.Wait()
+
+	_, cols := outputs[0].Dims()
+	result := mat.NewDense(rows, cols, nil)
+	for k, output := range outputs {
+		from := k * rows / n
+		r, _ := output.Dims()
+		result.Slice(from, from+r, 0, cols).(*mat.Dense).Copy(output)
+	}
+	return result, nil
+}
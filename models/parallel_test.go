@@ -0,0 +1,134 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// blobs returns n samples of three features whose class depends on the
+// sign of the sum of the first two, with one-hot targets
+func blobs(n int, seed int64) ([][]float64, [][]float64) {
+	rng := rand.New(rand.NewSource(seed))
+	X := make([][]float64, n)
+	Y := make([][]float64, n)
+	for i := range X {
+		X[i] = []float64{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+		if X[i][0]+X[i][1] > 0 {
+			Y[i] = []float64{0, 1}
+		} else {
+			Y[i] = []float64{1, 0}
+		}
+	}
+	return X, Y
+}
+
+// trainBlobs fits a small classifier on blobs with the given number of
+// workers, without shuffling so that only the workers change between runs
+func trainBlobs(t *testing.T, workers int, hogwild bool, epochs int) *MLPClassifier {
+	t.Helper()
+	X, Y := blobs(64, 5)
+	mlp := NewMLPClassifier(3, []int{8}, 2, 0.1, rand.NewSource(1))
+	mlp.Shuffle = false
+	mlp.BatchSize = 16
+	mlp.Workers = workers
+	mlp.Hogwild = hogwild
+	if _, err := mlp.Fit(X, Y, epochs); err != nil {
+		t.Fatal(err)
+	}
+	return mlp
+}
+
+func TestParallelGradientsMatchSingleWorker(t *testing.T) {
+	X, Y := blobs(37, 3)
+	inputs, targets := rowsToDense(X), rowsToDense(Y)
+	single := NewMLPClassifier(3, []int{8, 4}, 2, 0.1, rand.NewSource(2))
+	single.fitBatch(inputs, targets, NewSGD(0, 0, false), single.loss(), nil)
+
+	for _, n := range []int{2, 3, 5} {
+		parallel := NewMLPClassifier(3, []int{8, 4}, 2, 0.1, rand.NewSource(2))
+		parallel.Workers = n
+		workers, err := parallel.newWorkers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parallel.fitBatchParallel(workers, inputs, targets, NewSGD(0, 0, false), parallel.loss(), nil)
+
+		// Sharding regroups the sums over the rows, which changes the
+		// rounding: allow a few ulps of the largest gradient of each
+		// parameter, since small elements come out of cancellations
+		want, got := single.parameters(), parallel.parameters()
+		for i := range want {
+			scale := 0.0
+			for _, g := range rawData(want[i].Grad) {
+				scale = math.Max(scale, math.Abs(g))
+			}
+			for j, g := range rawData(want[i].Grad) {
+				h := rawData(got[i].Grad)[j]
+				if math.Abs(g-h) > 8*0x1p-52*scale {
+					t.Errorf("%d workers: parameter %d element %d: gradient %v, expected %v", n, i, j, h, g)
+				}
+			}
+		}
+	}
+}
+
+func TestParallelTrainingIsDeterministic(t *testing.T) {
+	single := trainBlobs(t, 1, false, 5)
+	first := trainBlobs(t, 4, false, 5)
+	second := trainBlobs(t, 4, false, 5)
+	want, a, b := single.parameters(), first.parameters(), second.parameters()
+	for i := range want {
+		for j, v := range rawData(want[i].Value) {
+			x, y := rawData(a[i].Value)[j], rawData(b[i].Value)[j]
+			if x != y {
+				t.Fatalf("parameter %d element %d differs between runs: %v and %v", i, j, x, y)
+			}
+			// Sharding only reorders the floating-point sums
+			if math.Abs(x-v) > 1e-12 {
+				t.Errorf("parameter %d element %d: %v with 4 workers, %v with one", i, j, x, v)
+			}
+		}
+	}
+}
+
+func TestHogwildTraining(t *testing.T) {
+	mlp := trainBlobs(t, 4, true, 100)
+	X, Y := blobs(64, 5)
+	correct := 0
+	for i, row := range X {
+		class, err := mlp.Predict(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Y[i][class] == 1 {
+			correct++
+		}
+	}
+	if accuracy := float64(correct) / float64(len(X)); accuracy < 0.85 {
+		t.Errorf("Hogwild training accuracy %v, expected at least 0.85", accuracy)
+	}
+}
+
+func TestHogwildEmbeddingTraining(t *testing.T) {
+	X, Y := blobs(64, 6)
+	for i := range X {
+		X[i][2] = float64(i % 4) // ID column
+	}
+	mlp := NewMLPClassifier(3, []int{8}, 2, 0.1, rand.NewSource(1))
+	if err := mlp.EmbedColumn(2, 6, 2); err != nil {
+		t.Fatal(err)
+	}
+	unused := append([]float64(nil), mlp.Layers[0].(*Embedding).Table.Value.RawRowView(5)...)
+	mlp.BatchSize = 8
+	mlp.Workers = 4
+	mlp.Hogwild = true
+	if _, err := mlp.Fit(X, Y, 5); err != nil {
+		t.Fatal(err)
+	}
+	for j, v := range mlp.Layers[0].(*Embedding).Table.Value.RawRowView(5) {
+		if v != unused[j] {
+			t.Errorf("unused embedding row changed at %d: %v, was %v", j, v, unused[j])
+		}
+	}
+}
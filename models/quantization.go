@@ -0,0 +1,464 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// QuantizedMLP runs a trained MLPClassifier with float32 or int8 weights
+// for cheaper inference. Dense layers become float32 matrices or int8
+// matrices with a scale and zero point per output channel; batch
+// normalization is folded into the preceding dense layer when it has no
+// activation, and dropout and noise layers are dropped.
+type QuantizedMLP struct {
+	Precision string // "float32" or "int8"
+
+	inputNodes     int
+	stages         []inferenceStage
+	sigmoidOutputs bool
+}
+
+// inferenceStage is one step of a low-precision inference path. Values
+// travel between stages as float32
+type inferenceStage interface {
+	apply(x []float32) []float32
+	weightBytes() int
+}
+
+// Float32 converts the trained network to float32 inference
+func (mlp *MLPClassifier) Float32() (*QuantizedMLP, error) {
+	stages, err := mlp.inferenceStages()
+	if err != nil {
+		return nil, err
+	}
+	q := &QuantizedMLP{Precision: "float32", inputNodes: mlp.InputNodes, sigmoidOutputs: mlp.sigmoidOutputs()}
+	for _, s := range stages {
+		if d, ok := s.(*denseStage); ok {
+			q.stages = append(q.stages, d.float32())
+		} else {
+			q.stages = append(q.stages, s)
+		}
+	}
+	return q, nil
+}
+
+// QuantizeInt8 converts the trained network to int8 inference. Weights get
+// a scale and zero point per output channel; the input range of every
+// dense layer, which sets the scale and zero point of its activations, is
+// calibrated on the given representative samples.
+func (mlp *MLPClassifier) QuantizeInt8(calibration [][]float64) (*QuantizedMLP, error) {
+	if len(calibration) == 0 {
+		return nil, errors.New("calibration data is empty")
+	}
+	for i, row := range calibration {
+		if len(row) != mlp.InputNodes {
+			return nil, fmt.Errorf("row %d of calibration data has %d features, expected %d", i, len(row), mlp.InputNodes)
+		}
+	}
+	stages, err := mlp.inferenceStages()
+	if err != nil {
+		return nil, err
+	}
+	f32, err := mlp.Float32()
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the calibration samples in float32 and record the range seen at
+	// the input of every stage; zero stays representable
+	low := make([]float32, len(stages))
+	high := make([]float32, len(stages))
+	for _, row := range calibration {
+		x := toFloat32(row)
+		for i, s := range f32.stages {
+			for _, v := range x {
+				low[i] = min(low[i], v)
+				high[i] = max(high[i], v)
+			}
+			x = s.apply(x)
+		}
+	}
+
+	q := &QuantizedMLP{Precision: "int8", inputNodes: mlp.InputNodes, sigmoidOutputs: mlp.sigmoidOutputs()}
+	for i, s := range stages {
+		if d, ok := s.(*denseStage); ok {
+			q.stages = append(q.stages, d.int8(low[i], high[i]))
+		} else {
+			q.stages = append(q.stages, s)
+		}
+	}
+	return q, nil
+}
+
+// inferenceStages translates the layers into float64 dense stages and the
+// stages shared by every precision
+func (mlp *MLPClassifier) inferenceStages() ([]inferenceStage, error) {
+	var stages []inferenceStage
+	for i, layer := range mlp.Layers {
+		switch l := layer.(type) {
+		case *DenseLayer:
+			inputs, outputs := l.Weights.Value.Dims()
+			d := &denseStage{inputs: inputs, outputs: outputs,
+				weights: append([]float64(nil), rawData(l.Weights.Value)...),
+				bias:    append([]float64(nil), l.Bias.Value.RawRowView(0)...)}
+			stages = append(stages, d)
+			if _, linear := l.Activation.(Identity); !linear {
+				stages = append(stages, activationStage{l.Activation})
+			}
+		case *BatchNormLayer:
+			scale := make([]float64, len(l.RunningMean))
+			shift := make([]float64, len(l.RunningMean))
+			gamma, beta := l.Gamma.Value.RawRowView(0), l.Beta.Value.RawRowView(0)
+			for j := range scale {
+				scale[j] = gamma[j] / math.Sqrt(l.RunningVar[j]+l.Epsilon)
+				shift[j] = beta[j] - scale[j]*l.RunningMean[j]
+			}
+			if len(stages) > 0 {
+				if d, ok := stages[len(stages)-1].(*denseStage); ok {
+					d.fold(scale, shift)
+					continue
+				}
+			}
+			stages = append(stages, &scaleShiftStage{scale: toFloat32(scale), shift: toFloat32(shift)})
+		case *ActivationLayer:
+			stages = append(stages, activationStage{l.Activation})
+		case *DropoutLayer, *GaussianNoiseLayer, *Flatten:
+			// No-ops at inference time
+		default:
+			return nil, fmt.Errorf("layer %d (%T) cannot be quantized", i, layer)
+		}
+	}
+	return stages, nil
+}
+
+// toFloat32 converts a slice to float32
+func toFloat32(values []float64) []float32 {
+	result := make([]float32, len(values))
+	for i, v := range values {
+		result[i] = float32(v)
+	}
+	return result
+}
+
+// denseStage holds the float64 weights of a dense layer, inputs x outputs
+// in row-major order, before conversion to a lower precision
+type denseStage struct {
+	inputs, outputs int
+	weights         []float64
+	bias            []float64
+}
+
+// fold applies a per-output scale and shift to the weights and the bias
+func (d *denseStage) fold(scale, shift []float64) {
+	for i := 0; i < d.inputs; i++ {
+		for j := 0; j < d.outputs; j++ {
+			d.weights[i*d.outputs+j] *= scale[j]
+		}
+	}
+	for j := range d.bias {
+		d.bias[j] = d.bias[j]*scale[j] + shift[j]
+	}
+}
+
+func (d *denseStage) apply(x []float32) []float32 { return d.float32().apply(x) }
+
+func (d *denseStage) weightBytes() int { return 8 * (len(d.weights) + len(d.bias)) }
+
+func (d *denseStage) float32() *float32Dense {
+	return &float32Dense{inputs: d.inputs, outputs: d.outputs, weights: toFloat32(d.weights), bias: toFloat32(d.bias)}
+}
+
+// int8 quantizes the weights with an asymmetric scale and zero point per
+// output channel, for inputs calibrated to [low, high]
+func (d *denseStage) int8(low, high float32) *int8Dense {
+	q := &int8Dense{
+		inputs:      d.inputs,
+		outputs:     d.outputs,
+		weights:     make([]int8, len(d.weights)),
+		weightScale: make([]float32, d.outputs),
+		weightZero:  make([]int32, d.outputs),
+		bias:        toFloat32(d.bias),
+	}
+	q.inputScale, q.inputZero = quantizationParams(float64(low), float64(high))
+	for j := 0; j < d.outputs; j++ {
+		lo, hi := 0.0, 0.0
+		for i := 0; i < d.inputs; i++ {
+			lo = math.Min(lo, d.weights[i*d.outputs+j])
+			hi = math.Max(hi, d.weights[i*d.outputs+j])
+		}
+		q.weightScale[j], q.weightZero[j] = quantizationParams(lo, hi)
+		for i := 0; i < d.inputs; i++ {
+			q.weights[i*d.outputs+j] = quantize(float32(d.weights[i*d.outputs+j]), q.weightScale[j], q.weightZero[j])
+		}
+	}
+	return q
+}
+
+// quantizationParams maps [low, high], which must contain 0, onto the 256
+// int8 levels
+func quantizationParams(low, high float64) (float32, int32) {
+	scale := (high - low) / 255
+	if scale == 0 {
+		return 1, 0
+	}
+	zero := math.Round(-128 - low/scale)
+	return float32(scale), int32(math.Max(-128, math.Min(127, zero)))
+}
+
+// quantize rounds v to the nearest int8 level
+func quantize(v, scale float32, zero int32) int8 {
+	q := math.Round(float64(v/scale)) + float64(zero)
+	return int8(math.Max(-128, math.Min(127, q)))
+}
+
+// float32Dense is a dense layer computed in float32
+type float32Dense struct {
+	inputs, outputs int
+	weights         []float32
+	bias            []float32
+}
+
+func (d *float32Dense) apply(x []float32) []float32 {
+	y := append([]float32(nil), d.bias...)
+	for i, v := range x {
+		row := d.weights[i*d.outputs : (i+1)*d.outputs]
+		for j, w := range row {
+			y[j] += v * w
+		}
+	}
+	return y
+}
+
+func (d *float32Dense) weightBytes() int { return 4 * (len(d.weights) + len(d.bias)) }
+
+// int8Dense is a dense layer with int8 inputs and weights accumulated in
+// int32: y_j = inputScale*weightScale_j * sum_i (x_i - inputZero)(w_ij - weightZero_j) + bias_j
+type int8Dense struct {
+	inputs, outputs int
+	weights         []int8
+	weightScale     []float32
+	weightZero      []int32
+	inputScale      float32
+	inputZero       int32
+	bias            []float32
+}
+
+func (d *int8Dense) apply(x []float32) []float32 {
+	acc := make([]int32, d.outputs)
+	for i, v := range x {
+		qx := int32(quantize(v, d.inputScale, d.inputZero)) - d.inputZero
+		row := d.weights[i*d.outputs : (i+1)*d.outputs]
+		for j, w := range row {
+			acc[j] += qx * (int32(w) - d.weightZero[j])
+		}
+	}
+	y := make([]float32, d.outputs)
+	for j := range y {
+		y[j] = d.inputScale*d.weightScale[j]*float32(acc[j]) + d.bias[j]
+	}
+	return y
+}
+
+func (d *int8Dense) weightBytes() int {
+	return len(d.weights) + 4*(len(d.weightScale)+len(d.weightZero)+len(d.bias)) + 8
+}
+
+// scaleShiftStage is a batch normalization that could not be folded
+type scaleShiftStage struct {
+	scale, shift []float32
+}
+
+func (s *scaleShiftStage) apply(x []float32) []float32 {
+	y := make([]float32, len(x))
+	for j, v := range x {
+		y[j] = s.scale[j]*v + s.shift[j]
+	}
+	return y
+}
+
+func (s *scaleShiftStage) weightBytes() int { return 4 * (len(s.scale) + len(s.shift)) }
+
+// activationStage applies an activation element-wise
+type activationStage struct {
+	activation Activation
+}
+
+func (s activationStage) apply(x []float32) []float32 {
+	y := make([]float32, len(x))
+	for j, v := range x {
+		y[j] = float32(s.activation.Activate(float64(v)))
+	}
+	return y
+}
+
+func (s activationStage) weightBytes() int { return 0 }
+
+// logits runs a sample through every stage
+func (q *QuantizedMLP) logits(input []float64) []float64 {
+	x := toFloat32(input)
+	for _, s := range q.stages {
+		x = s.apply(x)
+	}
+	logits := make([]float64, len(x))
+	for i, v := range x {
+		logits[i] = float64(v)
+	}
+	return logits
+}
+
+// WeightBytes returns the memory taken by the weights, biases and
+// quantization parameters
+func (q *QuantizedMLP) WeightBytes() int {
+	total := 0
+	for _, s := range q.stages {
+		total += s.weightBytes()
+	}
+	return total
+}
+
+// PredictProba returns the class probabilities like
+// MLPClassifier.PredictProba
+func (q *QuantizedMLP) PredictProba(input []float64) ([]float64, error) {
+	if len(input) != q.inputNodes {
+		return nil, fmt.Errorf("input has %d features, expected %d", len(input), q.inputNodes)
+	}
+	proba := q.logits(input)
+	if !q.sigmoidOutputs {
+		return softmax(proba), nil
+	}
+	for i := range proba {
+		proba[i] = sigmoid(proba[i])
+	}
+	return proba, nil
+}
+
+// Predict returns the most likely class like MLPClassifier.Predict
+func (q *QuantizedMLP) Predict(input []float64) (int, error) {
+	proba, err := q.PredictProba(input)
+	if err != nil {
+		return 0, err
+	}
+	if len(proba) == 1 {
+		if proba[0] >= 0.5 {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return argmax(proba), nil
+}
+
+// PredictMultilabel returns, for each output node, whether its sigmoid
+// probability is at least 0.5
+func (q *QuantizedMLP) PredictMultilabel(input []float64) ([]bool, error) {
+	proba, err := q.PredictProba(input)
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]bool, len(proba))
+	for i, p := range proba {
+		labels[i] = p >= 0.5
+	}
+	return labels, nil
+}
+
+// QuantizationReport compares the predictions of the float64 model with
+// its float32 and int8 versions on a labelled data set
+type QuantizationReport struct {
+	Samples int
+
+	Float64Accuracy float64
+	Float32Accuracy float64
+	Int8Accuracy    float64
+
+	// Fraction of the samples predicted like the float64 model
+	Float32Agreement float64
+	Int8Agreement    float64
+
+	// Largest absolute difference with the float64 probabilities
+	Float32MaxError float64
+	Int8MaxError    float64
+
+	Float64Bytes int
+	Float32Bytes int
+	Int8Bytes    int
+}
+
+// CompareQuantized builds the float32 and int8 versions of the network,
+// calibrating the latter on calibration, and reports how they perform on
+// X against the targets Y (one-hot or multilabel rows, as for Fit)
+func (mlp *MLPClassifier) CompareQuantized(X, Y, calibration [][]float64) (*QuantizationReport, error) {
+	if err := mlp.checkData(X, Y); err != nil {
+		return nil, err
+	}
+	f32, err := mlp.Float32()
+	if err != nil {
+		return nil, err
+	}
+	i8, err := mlp.QuantizeInt8(calibration)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &QuantizationReport{Samples: len(X), Float32Bytes: f32.WeightBytes(), Int8Bytes: i8.WeightBytes()}
+	for _, p := range mlp.parameters() {
+		report.Float64Bytes += 8 * len(rawData(p.Value))
+	}
+
+	outputs := mlp.forward(rowsToDense(X), false)
+	n := float64(len(X))
+	for _, q := range []struct {
+		model                      *QuantizedMLP
+		accuracy, agreement, error *float64
+	}{
+		{f32, &report.Float32Accuracy, &report.Float32Agreement, &report.Float32MaxError},
+		{i8, &report.Int8Accuracy, &report.Int8Agreement, &report.Int8MaxError},
+	} {
+		for i, row := range X {
+			logits, qLogits := outputs.RawRowView(i), q.model.logits(row)
+			proba, qProba := mlp.probabilities(logits), mlp.probabilities(qLogits)
+			for j := range proba {
+				*q.error = math.Max(*q.error, math.Abs(proba[j]-qProba[j]))
+			}
+			if rowCorrect(qLogits, Y[i], mlp.sigmoidOutputs()) {
+				*q.accuracy += 1 / n
+			}
+			if rowCorrect(qLogits, proba, mlp.sigmoidOutputs()) {
+				*q.agreement += 1 / n
+			}
+		}
+	}
+	report.Float64Accuracy = float64(mlp.correct(outputs, rowsToDense(Y))) / n
+	return report, nil
+}
+
+// rowCorrect reports whether the labels predicted from logits match target
+func rowCorrect(logits, target []float64, sigmoidOutputs bool) bool {
+	return countCorrect(mat.NewDense(1, len(logits), logits), mat.NewDense(1, len(target), target), sigmoidOutputs) == 1
+}
+
+// probabilities maps logits to the probabilities of the output layer
+func (mlp *MLPClassifier) probabilities(logits []float64) []float64 {
+	if !mlp.sigmoidOutputs() {
+		return softmax(logits)
+	}
+	proba := make([]float64, len(logits))
+	for i, z := range logits {
+		proba[i] = sigmoid(z)
+	}
+	return proba
+}
+
+// String formats the report as a table
+func (r *QuantizationReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Quantization report on %d samples\n", r.Samples)
+	fmt.Fprintf(&b, "%-10s %10s %10s %10s %10s\n", "precision", "accuracy", "agreement", "max error", "bytes")
+	fmt.Fprintf(&b, "%-10s %10.4f %10s %10s %10d\n", "float64", r.Float64Accuracy, "-", "-", r.Float64Bytes)
+	fmt.Fprintf(&b, "%-10s %10.4f %10.4f %10.6f %10d\n", "float32", r.Float32Accuracy, r.Float32Agreement, r.Float32MaxError, r.Float32Bytes)
+	fmt.Fprintf(&b, "%-10s %10.4f %10.4f %10.6f %10d\n", "int8", r.Int8Accuracy, r.Int8Agreement, r.Int8MaxError, r.Int8Bytes)
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// trainQuantizable fits a classifier with batch normalization, which the
+// quantized versions fold into the preceding dense layer
+func trainQuantizable(t *testing.T) (*MLPClassifier, [][]float64, [][]float64) {
+	t.Helper()
+	X, Y := blobs(128, 8)
+	mlp := NewMLPClassifier(3, nil, 2, 0.05, nil)
+	mlp.Layers = []Layer{
+		NewDenseLayer(3, 16, Identity{}), NewBatchNormLayer(16), NewActivationLayer(ReLU{}),
+		NewDenseLayer(16, 2, Identity{}),
+	}
+	mlp.Initialize(rand.NewSource(3))
+	if _, err := mlp.Fit(X, Y, 30); err != nil {
+		t.Fatal(err)
+	}
+	return mlp, X, Y
+}
+
+func TestFloat32MatchesFloat64(t *testing.T) {
+	mlp, X, _ := trainQuantizable(t)
+	q, err := mlp.Float32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range X {
+		want, err := mlp.PredictProba(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := q.PredictProba(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := range want {
+			if math.Abs(got[j]-want[j]) > 1e-5 {
+				t.Errorf("row %d class %d: float32 probability %v, float64 %v", i, j, got[j], want[j])
+			}
+		}
+	}
+}
+
+func TestInt8AgreesWithFloat64(t *testing.T) {
+	mlp, X, Y := trainQuantizable(t)
+	report, err := mlp.CompareQuantized(X, Y, X[:64])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Float32Agreement != 1 {
+		t.Errorf("float32 agreement %v, expected 1", report.Float32Agreement)
+	}
+	if report.Int8Agreement < 0.95 {
+		t.Errorf("int8 agreement %v, expected at least 0.95", report.Int8Agreement)
+	}
+	if report.Int8Bytes >= report.Float32Bytes || report.Float32Bytes >= report.Float64Bytes {
+		t.Errorf("weights take %d, %d and %d bytes, expected them to shrink with the precision",
+			report.Float64Bytes, report.Float32Bytes, report.Int8Bytes)
+	}
+}
+
+func TestQuantizedPredictRejectsWrongWidth(t *testing.T) {
+	mlp, X, _ := trainQuantizable(t)
+	i8, err := mlp.QuantizeInt8(X)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i8.Predict([]float64{1, 2}); err == nil {
+		t.Error("Predict: expected an error for 2 features")
+	}
+	if _, err := i8.PredictProba([]float64{1, 2, 3, 4}); err == nil {
+		t.Error("PredictProba: expected an error for 4 features")
+	}
+	if _, err := i8.PredictMultilabel(nil); err == nil {
+		t.Error("PredictMultilabel: expected an error for no features")
+	}
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/snugml/go/autograd"
+	"gonum.org/v1/gonum/mat"
+)
+
+// The recurrent layers work on sequences stored one per row, time step
+// after time step: feature f of step t is at column t*features + f. They
+// unroll the sequence into an autograd graph, so Backward performs
+// backpropagation through time.
+
+// recurrent holds what SimpleRNN, GRU and LSTM share: the stacked gate
+// parameters, the sequence layout and the masking and output modes
+type recurrent struct {
+	TimeSteps, Features, Units int
+
+	// ReturnSequences outputs the hidden state of every step
+	// (many-to-many, TimeSteps*Units columns) instead of only the last one
+	// (many-to-one, Units columns)
+	ReturnSequences bool
+
+	// Masking skips the steps whose features all equal MaskValue, so that
+	// shorter sequences can be padded: the state is carried over unchanged
+	// and the step outputs zeros when ReturnSequences is set
+	Masking   bool
+	MaskValue float64
+
+	Weights   *Parameter // features x gates*units, gates side by side
+	Recurrent *Parameter // units x gates*units
+	Bias      *Parameter // 1 x gates*units
+
+	// WeightInit, RecurrentInit and BiasInit fill the parameters when the
+	// layer is created or reinitialized
+	WeightInit    Initializer
+	RecurrentInit Initializer
+	BiasInit      Initializer
+
+	tape *tape
+}
+
+// newRecurrent creates the parameters for the given number of gates with
+// Xavier uniform input weights, orthogonal recurrent weights and zero
+// biases
+func newRecurrent(timeSteps, features, units, gates int, returnSequences bool) recurrent {
+	r := recurrent{
+		TimeSteps:       timeSteps,
+		Features:        features,
+		Units:           units,
+		ReturnSequences: returnSequences,
+		Weights:         newParameter(mat.NewDense(features, gates*units, nil)),
+		Recurrent:       newParameter(mat.NewDense(units, gates*units, nil)),
+		Bias:            newParameter(mat.NewDense(1, gates*units, nil)),
+		WeightInit:      XavierUniform{},
+		RecurrentInit:   Orthogonal{},
+		BiasInit:        Zeros{},
+	}
+	r.Weights.Regularize = true
+	r.Recurrent.Regularize = true
+	r.Initialize(nil)
+	return r
+}
+
+// Initialize redraws the parameters from their initializers using rng, or
+// the global math/rand source when rng is nil. Every gate gets its own
+// recurrent matrix, so orthogonal recurrent weights stay orthogonal
+func (r *recurrent) Initialize(rng *rand.Rand) {
+	_, cols := r.Weights.Value.Dims()
+	r.Weights.Value.Copy(r.WeightInit.Initialize(r.Features, cols, rng))
+	for g := 0; g < cols/r.Units; g++ {
+		block := r.Recurrent.Value.Slice(0, r.Units, g*r.Units, (g+1)*r.Units).(*mat.Dense)
+		block.Copy(r.RecurrentInit.Initialize(r.Units, r.Units, rng))
+	}
+	r.Bias.Value.Copy(r.BiasInit.Initialize(1, cols, rng))
+}
+
+// OutputSize returns the number of output columns
+func (r *recurrent) OutputSize() int {
+	if r.ReturnSequences {
+		return r.TimeSteps * r.Units
+	}
+	return r.Units
+}
+
+func (r *recurrent) outputWidth(inputs int) (int, error) {
+	if inputs != r.TimeSteps*r.Features {
+		return 0, fmt.Errorf("%d inputs, expected %d steps of %d features", inputs, r.TimeSteps, r.Features)
+	}
+	return r.OutputSize(), nil
+}
+
+// Parameters returns the input weights, the recurrent weights and the bias
+func (r *recurrent) Parameters() []*Parameter {
+	return []*Parameter{r.Weights, r.Recurrent, r.Bias}
+}
+
+func (r *recurrent) Backward(outputGradient *mat.Dense) *mat.Dense {
+	return r.tape.backward(outputGradient)
+}
+
+// gate holds the slices of the parameters belonging to one gate, with the
+// bias already broadcast to the batch
+type gate struct {
+	w, u, b *autograd.Variable
+}
+
+// affine returns x*w + h*u + b
+func (g gate) affine(x, h *autograd.Variable) *autograd.Variable {
+	return autograd.Add(autograd.Add(autograd.MatMul(x, g.w), autograd.MatMul(h, g.u)), g.b)
+}
+
+// cellFunc computes the next hidden state and, for LSTM, the next cell
+// state of one time step
+type cellFunc func(x, h, c *autograd.Variable, gates []gate) (*autograd.Variable, *autograd.Variable)
+
+// forward unrolls the sequence with the given cell and records the graph
+func (r *recurrent) forward(input *mat.Dense, training bool, cell cellFunc) *mat.Dense {
+	samples, _ := input.Dims()
+	t := newTape(input, training, r.Weights, r.Recurrent, r.Bias)
+
+	_, cols := r.Weights.Value.Dims()
+	gates := make([]gate, cols/r.Units)
+	for g := range gates {
+		from, to := g*r.Units, (g+1)*r.Units
+		gates[g] = gate{
+			w: autograd.SliceCols(t.vars[0], from, to),
+			u: autograd.SliceCols(t.vars[1], from, to),
+			b: autograd.BroadcastRows(autograd.SliceCols(t.vars[2], from, to), samples),
+		}
+	}
+
+	h := autograd.Constant(mat.NewDense(samples, r.Units, nil))
+	c := autograd.Constant(mat.NewDense(samples, r.Units, nil))
+	var outputs []*autograd.Variable
+	for step := 0; step < r.TimeSteps; step++ {
+		x := autograd.SliceCols(t.input, step*r.Features, (step+1)*r.Features)
+		hNext, cNext := cell(x, h, c, gates)
+		output := hNext
+		if r.Masking {
+			keep, skip := r.stepMask(input, step)
+			output = autograd.Mul(keep, hNext)
+			hNext = autograd.Add(output, autograd.Mul(skip, h))
+			if cNext != nil {
+				cNext = autograd.Add(autograd.Mul(keep, cNext), autograd.Mul(skip, c))
+			}
+		}
+		h, c = hNext, cNext
+		if r.ReturnSequences {
+			outputs = append(outputs, output)
+		}
+	}
+
+	t.output = h
+	if r.ReturnSequences {
+		t.output = autograd.ConcatCols(outputs...)
+	}
+	r.tape = t
+	return t.output.Value
+}
+
+// stepMask returns samples x units constants that are 1 (keep) and 0
+// (skip) for the samples whose step is real, and the other way round for
+// padded steps
+func (r *recurrent) stepMask(input *mat.Dense, step int) (*autograd.Variable, *autograd.Variable) {
+	samples, _ := input.Dims()
+	keep := mat.NewDense(samples, r.Units, nil)
+	skip := mat.NewDense(samples, r.Units, nil)
+	for i := 0; i < samples; i++ {
+		padded := true
+		for _, v := range input.RawRowView(i)[step*r.Features : (step+1)*r.Features] {
+			if v != r.MaskValue {
+				padded = false
+				break
+			}
+		}
+		row := keep.RawRowView(i)
+		if padded {
+			row = skip.RawRowView(i)
+		}
+		for j := range row {
+			row[j] = 1
+		}
+	}
+	return autograd.Constant(keep), autograd.Constant(skip)
+}
+
+// oneMinus returns 1 - v
+func oneMinus(v *autograd.Variable) *autograd.Variable {
+	return autograd.AddScalar(autograd.Scale(v, -1), 1)
+}
+
+// SimpleRNN is the Elman recurrent layer h' = activation(x*W + h*U + b)
+type SimpleRNN struct {
+	recurrent
+	Activation Activation
+}
+
+// NewSimpleRNN creates a recurrent layer over sequences of timeSteps steps
+// of the given number of features, with tanh activation
+func NewSimpleRNN(timeSteps, features, units int, returnSequences bool) *SimpleRNN {
+	return &SimpleRNN{
+		recurrent:  newRecurrent(timeSteps, features, units, 1, returnSequences),
+		Activation: Tanh{},
+	}
+}
+
+func (l *SimpleRNN) Forward(input *mat.Dense, training bool) *mat.Dense {
+	return l.forward(input, training, func(x, h, _ *autograd.Variable, gates []gate) (*autograd.Variable, *autograd.Variable) {
+		return applyActivation(gates[0].affine(x, h), l.Activation), nil
+	})
+}
+
+// GRU is the gated recurrent unit with update gate z, reset gate r and
+// candidate n: h' = (1-z)*n + z*h
+type GRU struct {
+	recurrent
+}
+
+// NewGRU creates a GRU layer over sequences of timeSteps steps of the
+// given number of features
+func NewGRU(timeSteps, features, units int, returnSequences bool) *GRU {
+	return &GRU{newRecurrent(timeSteps, features, units, 3, returnSequences)}
+}
+
+func (l *GRU) Forward(input *mat.Dense, training bool) *mat.Dense {
+	return l.forward(input, training, func(x, h, _ *autograd.Variable, gates []gate) (*autograd.Variable, *autograd.Variable) {
+		z := autograd.Sigmoid(gates[0].affine(x, h))
+		r := autograd.Sigmoid(gates[1].affine(x, h))
+		n := autograd.Tanh(gates[2].affine(x, autograd.Mul(r, h)))
+		return autograd.Add(autograd.Mul(oneMinus(z), n), autograd.Mul(z, h)), nil
+	})
+}
+
+// LSTM is the long short-term memory layer with input, forget and output
+// gates i, f, o and candidate g: c' = f*c + i*g and h' = o*tanh(c')
+type LSTM struct {
+	recurrent
+}
+
+// NewLSTM creates an LSTM layer over sequences of timeSteps steps of the
+// given number of features
+func NewLSTM(timeSteps, features, units int, returnSequences bool) *LSTM {
+	return &LSTM{newRecurrent(timeSteps, features, units, 4, returnSequences)}
+}
+
+func (l *LSTM) Forward(input *mat.Dense, training bool) *mat.Dense {
+	return l.forward(input, training, func(x, h, c *autograd.Variable, gates []gate) (*autograd.Variable, *autograd.Variable) {
+		i := autograd.Sigmoid(gates[0].affine(x, h))
+		f := autograd.Sigmoid(gates[1].affine(x, h))
+		o := autograd.Sigmoid(gates[2].affine(x, h))
+		g := autograd.Tanh(gates[3].affine(x, h))
+		cNext := autograd.Add(autograd.Mul(f, c), autograd.Mul(i, g))
+		return autograd.Mul(o, autograd.Tanh(cNext)), cNext
+	})
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// recurrentConstructors builds each recurrent layer type
+var recurrentConstructors = []struct {
+	name  string
+	build func(timeSteps, features, units int, returnSequences bool) Layer
+}{
+	{"SimpleRNN", func(t, f, u int, r bool) Layer { return NewSimpleRNN(t, f, u, r) }},
+	{"GRU", func(t, f, u int, r bool) Layer { return NewGRU(t, f, u, r) }},
+	{"LSTM", func(t, f, u int, r bool) Layer { return NewLSTM(t, f, u, r) }},
+}
+
+// recurrentOf returns the state shared by the recurrent layers
+func recurrentOf(l Layer) *recurrent {
+	switch l := l.(type) {
+	case *SimpleRNN:
+		return &l.recurrent
+	case *GRU:
+		return &l.recurrent
+	case *LSTM:
+		return &l.recurrent
+	}
+	return nil
+}
+
+func TestRecurrentMaskingMatchesUnpaddedSequences(t *testing.T) {
+	const maxSteps, features, units = 5, 2, 3
+	rng := rand.New(rand.NewSource(1))
+	lengths := []int{2, 5, 3, 1}
+	sequences := gradientCheckRows(rng, len(lengths), maxSteps*features)
+	for i, n := range lengths {
+		for j := n * features; j < maxSteps*features; j++ {
+			sequences[i][j] = 0
+		}
+	}
+
+	for _, c := range recurrentConstructors {
+		name, build := c.name, c.build
+		for _, returnSequences := range []bool{false, true} {
+			padded := build(maxSteps, features, units, returnSequences)
+			recurrentOf(padded).Masking = true
+			recurrentOf(padded).Initialize(rng)
+			outputs := padded.Forward(rowsToDense(sequences), false)
+
+			for i, n := range lengths {
+				unpadded := build(n, features, units, returnSequences)
+				r, p := recurrentOf(unpadded), recurrentOf(padded)
+				r.Weights, r.Recurrent, r.Bias = p.Weights, p.Recurrent, p.Bias
+				want := unpadded.Forward(rowsToDense([][]float64{sequences[i][:n*features]}), false).RawRowView(0)
+
+				got := outputs.RawRowView(i)
+				if returnSequences {
+					// Padded steps output zeros after the real ones
+					for j, v := range got[n*units:] {
+						if v != 0 {
+							t.Errorf("%s sequences: row %d padded output %d is %v", name, i, j, v)
+						}
+					}
+					got = got[:n*units]
+				}
+				for j := range want {
+					if math.Abs(got[j]-want[j]) > 1e-12 {
+						t.Errorf("%s (sequences %v): row %d output %d is %v, unpadded %v", name, returnSequences, i, j, got[j], want[j])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRecurrentGradients(t *testing.T) {
+	const steps, features, units = 4, 2, 3
+	for _, c := range recurrentConstructors {
+		name, build := c.name, c.build
+		for _, returnSequences := range []bool{false, true} {
+			for _, masking := range []bool{false, true} {
+				rng := rand.New(rand.NewSource(2))
+				X := gradientCheckRows(rng, 5, steps*features)
+				if masking {
+					// Pad the last steps of some sequences
+					for i := range X {
+						for j := (i%steps + 1) * features; j < steps*features; j++ {
+							X[i][j] = 0
+						}
+					}
+				}
+				layer := build(steps, features, units, returnSequences)
+				recurrentOf(layer).Masking = masking
+				recurrentOf(layer).Initialize(rng)
+				outputs := recurrentOf(layer).OutputSize()
+				Y := gradientCheckRows(rng, 5, 2)
+
+				report, err := GradientCheck([]Layer{layer, NewDenseLayer(outputs, 2, Identity{})}, MeanSquaredError{}, X, Y, 0, rand.NewSource(1))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if report.MaxRelativeError > 1e-4 {
+					t.Errorf("%s (sequences %v, masking %v): max relative error %.3e\n%s", name, returnSequences, masking, report.MaxRelativeError, report)
+				}
+			}
+		}
+	}
+}
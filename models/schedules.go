@@ -0,0 +1,88 @@
+package models
+
+import "math"
+
+// LearningRateSchedule chooses the learning rate for each epoch. It
+// receives the zero-based epoch, the optimizer's rate when training
+// started and the loss of the previous epoch that early stopping also
+// monitors: the validation loss with a ValidationFraction, the mean
+// training loss otherwise (NaN before the first epoch has finished).
+type LearningRateSchedule interface {
+	LearningRate(epoch int, initialRate, lastLoss float64) float64
+}
+
+// StepDecay multiplies the rate by Gamma every StepSize epochs
+type StepDecay struct {
+	StepSize int
+	Gamma    float64
+}
+
+func (s StepDecay) LearningRate(epoch int, initialRate, lastLoss float64) float64 {
+	if s.StepSize <= 0 {
+		return initialRate
+	}
+	return initialRate * math.Pow(s.Gamma, float64(epoch/s.StepSize))
+}
+
+// ExponentialDecay multiplies the rate by Gamma every epoch
+type ExponentialDecay struct {
+	Gamma float64
+}
+
+func (s ExponentialDecay) LearningRate(epoch int, initialRate, lastLoss float64) float64 {
+	return initialRate * math.Pow(s.Gamma, float64(epoch))
+}
+
+// CosineAnnealing decreases the rate from its initial value to MinRate
+// along half a cosine wave over Epochs epochs
+type CosineAnnealing struct {
+	Epochs  int
+	MinRate float64
+}
+
+func (s CosineAnnealing) LearningRate(epoch int, initialRate, lastLoss float64) float64 {
+	if s.Epochs <= 0 {
+		return initialRate
+	}
+	progress := math.Min(float64(epoch)/float64(s.Epochs), 1)
+	return s.MinRate + 0.5*(initialRate-s.MinRate)*(1+math.Cos(math.Pi*progress))
+}
+
+// ReduceOnPlateau multiplies the rate by Factor when the monitored loss has
+// not improved by more than MinDelta for Patience epochs, never going
+// below MinRate. It is stateful, so use a new one for every training run.
+type ReduceOnPlateau struct {
+	Factor   float64
+	Patience int
+	MinDelta float64
+	MinRate  float64
+
+	rate      float64
+	bestLoss  float64
+	badEpochs int
+	started   bool
+}
+
+func (s *ReduceOnPlateau) LearningRate(epoch int, initialRate, lastLoss float64) float64 {
+	if !s.started {
+		s.started = true
+		s.rate = initialRate
+		s.bestLoss = math.Inf(1)
+	}
+	if math.IsNaN(lastLoss) {
+		return s.rate
+	}
+
+	if lastLoss < s.bestLoss-s.MinDelta {
+		s.bestLoss = lastLoss
+		s.badEpochs = 0
+		return s.rate
+	}
+
+	s.badEpochs++
+	if s.badEpochs >= s.Patience {
+		s.rate = math.Max(s.rate*s.Factor, s.MinRate)
+		s.badEpochs = 0
+	}
+	return s.rate
+}
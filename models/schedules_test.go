@@ -0,0 +1,40 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSchedules(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule LearningRateSchedule
+		want     []float64 // Rates of epochs 0, 1, ... from an initial rate of 1
+	}{
+		{"StepDecay", StepDecay{StepSize: 2, Gamma: 0.5}, []float64{1, 1, 0.5, 0.5, 0.25}},
+		{"StepDecay without steps", StepDecay{StepSize: 0, Gamma: 0.5}, []float64{1, 1, 1}},
+		{"ExponentialDecay", ExponentialDecay{Gamma: 0.9}, []float64{1, 0.9, 0.81, 0.729}},
+		// Half a cosine from 1 to 0.1 over 4 epochs, then held at the minimum
+		{"CosineAnnealing", CosineAnnealing{Epochs: 4, MinRate: 0.1},
+			[]float64{1, 0.1 + 0.45*(1+math.Sqrt2/2), 0.55, 0.1 + 0.45*(1-math.Sqrt2/2), 0.1, 0.1}},
+		{"CosineAnnealing without epochs", CosineAnnealing{MinRate: 0.1}, []float64{1, 1}},
+	}
+	for _, tt := range tests {
+		for epoch, want := range tt.want {
+			if got := tt.schedule.LearningRate(epoch, 1, math.NaN()); math.Abs(got-want) > 1e-12 {
+				t.Errorf("%s: epoch %d: rate %v, expected %v", tt.name, epoch, got, want)
+			}
+		}
+	}
+}
+
+func TestReduceOnPlateauWaitsPatienceEpochs(t *testing.T) {
+	s := &ReduceOnPlateau{Factor: 0.5, Patience: 2}
+	// The first loss is an improvement, the next two are not
+	want := []float64{1, 1, 0.5, 0.5, 0.25}
+	for epoch, w := range want {
+		if got := s.LearningRate(epoch, 1, 3); got != w {
+			t.Errorf("epoch %d: rate %v, expected %v", epoch, got, w)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Sequential is a model made of any stack of layers, for instance Conv2D
+// and pooling stages followed by Flatten and dense layers, trained with
+// the same loop, optimizers and callbacks as MLPClassifier. The number of
+// outputs is inferred from the layers. Fit and Predict first check that
+// the layers fit together, from InputNodes to the last layer, and report
+// a mismatch or a window larger than its images as an error.
+type Sequential struct {
+	Network
+	Loss Loss // nil uses SoftmaxCrossEntropy
+}
+
+// NewSequential creates a model for samples of inputNodes features with
+// the given layers, from input to output. Unless src is nil, the layers
+// are reinitialized from it and it seeds training like in
+// NewMLPClassifier
+func NewSequential(inputNodes int, learningRate float64, src rand.Source, layers ...Layer) *Sequential {
+	s := &Sequential{Network: Network{
+		InputNodes:   inputNodes,
+		LearningRate: learningRate,
+		BatchSize:    defaultBatchSize,
+		Shuffle:      true,
+	}}
+	s.Add(layers...)
+	if src != nil {
+		s.Initialize(src)
+	}
+	return s
+}
+
+// Add appends layers at the output end of the model
+func (s *Sequential) Add(layers ...Layer) {
+	s.Layers = append(s.Layers, layers...)
+}
+
+// loss returns the configured loss or SoftmaxCrossEntropy
+func (s *Sequential) loss() Loss {
+	if s.Loss == nil {
+		return SoftmaxCrossEntropy{}
+	}
+	return s.Loss
+}
+
+// accuracy counts correct rows for the classification losses and is nil
+// for the others
+func (s *Sequential) accuracy() accuracyFunc {
+	switch s.loss().(type) {
+	case SoftmaxCrossEntropy:
+		return func(outputs, targets *mat.Dense) int { return countCorrect(outputs, targets, false) }
+	case SigmoidBinaryCrossEntropy:
+		return func(outputs, targets *mat.Dense) int { return countCorrect(outputs, targets, true) }
+	}
+	return nil
+}
+
+// inferOutputs sets OutputNodes from a forward pass of a single blank
+// sample, once the layers are known to fit together
+func (s *Sequential) inferOutputs() error {
+	if len(s.Layers) == 0 {
+		return errors.New("the model has no layers")
+	}
+	if err := s.checkLayers(); err != nil {
+		return err
+	}
+	_, s.OutputNodes = s.forward(mat.NewDense(1, s.InputNodes, nil), false).Dims()
+	return nil
+}
+
+// Fit trains the model for the given number of epochs using mini-batch
+// gradient descent and returns the per-epoch History. Accuracy is only
+// recorded for the classification losses
+func (s *Sequential) Fit(X [][]float64, Y [][]float64, epochs int) (*History, error) {
+	return s.FitContext(context.Background(), X, Y, epochs)
+}
+
+// FitContext is Fit with cancellation, see MLPClassifier.FitContext
+func (s *Sequential) FitContext(ctx context.Context, X [][]float64, Y [][]float64, epochs int) (*History, error) {
+	if err := s.inferOutputs(); err != nil {
+		return nil, err
+	}
+	return s.fit(ctx, X, Y, epochs, s.loss(), s.accuracy())
+}
+
+// Predict returns the outputs of the last layer for every sample, which
+// are logits with the classification losses
+func (s *Sequential) Predict(X [][]float64) ([][]float64, error) {
+	if err := s.checkInputs(X); err != nil {
+		return nil, err
+	}
+	return denseToRows(s.forward(rowsToDense(X), false)), nil
+}
+
+// PredictClasses returns the index of the largest output of every sample
+func (s *Sequential) PredictClasses(X [][]float64) ([]int, error) {
+	outputs, err := s.Predict(X)
+	if err != nil {
+		return nil, err
+	}
+	classes := make([]int, len(outputs))
+	for i, row := range outputs {
+		classes[i] = argmax(row)
+	}
+	return classes, nil
+}
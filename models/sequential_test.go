@@ -0,0 +1,118 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// barImages returns 4x4 single-channel images holding either a vertical
+// (class 0) or a horizontal (class 1) bar at a random position
+func barImages(n int, rng *rand.Rand) ([][]float64, [][]float64) {
+	X := make([][]float64, n)
+	Y := make([][]float64, n)
+	for i := range X {
+		X[i] = make([]float64, 16)
+		for j := range X[i] {
+			X[i][j] = 0.1 * rng.Float64()
+		}
+		class, pos := i%2, rng.Intn(4)
+		for k := 0; k < 4; k++ {
+			if class == 0 {
+				X[i][k*4+pos] = 1
+			} else {
+				X[i][pos*4+k] = 1
+			}
+		}
+		Y[i] = make([]float64, 2)
+		Y[i][class] = 1
+	}
+	return X, Y
+}
+
+func newBarModel() *Sequential {
+	conv := NewConv2D(4, 4, 1, 4, 3, 1, 1, nil)
+	pool := NewMaxPool2D(4, 4, 4, 2, 0)
+	h, w, c := pool.OutputShape()
+	return NewSequential(16, 0.05, nil, conv, pool, NewFlatten(), NewDenseLayer(h*w*c, 2, Identity{}))
+}
+
+func TestSequentialInfersOutputs(t *testing.T) {
+	s := newBarModel()
+	if err := s.inferOutputs(); err != nil {
+		t.Fatal(err)
+	}
+	if s.OutputNodes != 2 {
+		t.Errorf("OutputNodes is %d, expected 2", s.OutputNodes)
+	}
+
+	X, Y := barImages(4, rand.New(rand.NewSource(1)))
+	for i := range Y {
+		Y[i] = append(Y[i], 0)
+	}
+	if _, err := s.Fit(X, Y, 1); err == nil {
+		t.Error("expected an error for 3 targets on 2 outputs")
+	}
+	if _, err := NewSequential(16, 0.1, nil).Fit(X, Y, 1); err == nil {
+		t.Error("expected an error for a model without layers")
+	}
+}
+
+func TestSequentialLearnsBars(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	X, Y := barImages(64, rng)
+	s := newBarModel()
+	s.Initialize(rand.NewSource(2))
+	s.Optimizer = NewAdam(0.01)
+	s.BatchSize = 16
+	if _, err := s.Fit(X, Y, 40); err != nil {
+		t.Fatal(err)
+	}
+
+	testX, testY := barImages(32, rng)
+	classes, err := s.PredictClasses(testX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	correct := 0
+	for i, class := range classes {
+		if testY[i][class] == 1 {
+			correct++
+		}
+	}
+	if accuracy := float64(correct) / float64(len(testX)); accuracy < 0.95 {
+		t.Errorf("test accuracy %v, expected at least 0.95", accuracy)
+	}
+}
+
+func TestSequentialRejectsMismatchedLayers(t *testing.T) {
+	X, Y := barImages(4, rand.New(rand.NewSource(1)))
+	cases := []struct {
+		name   string
+		inputs int
+		layers []Layer
+	}{
+		{"InputNodes", 15, newBarModel().Layers},
+		{"dense widths", 16, []Layer{NewDenseLayer(16, 8, ReLU{}), NewDenseLayer(6, 2, Identity{})}},
+		{"kernel larger than the image", 16, []Layer{NewConv2D(4, 4, 1, 2, 5, 1, 0, nil), NewFlatten(), NewDenseLayer(2, 2, Identity{})}},
+		{"strided kernel larger than the image", 16, []Layer{NewConv2D(4, 4, 1, 2, 5, 2, 0, nil), NewFlatten(), NewDenseLayer(2, 2, Identity{})}},
+		{"pool larger than the image", 16, []Layer{NewMaxPool2D(4, 4, 1, 5, 0), NewFlatten(), NewDenseLayer(1, 2, Identity{})}},
+		{"average pool larger than the image", 16, []Layer{NewAvgPool2D(4, 4, 1, 5, 0), NewFlatten(), NewDenseLayer(1, 2, Identity{})}},
+		{"recurrent steps", 16, []Layer{NewLSTM(3, 4, 2, false), NewDenseLayer(2, 2, Identity{})}},
+		{"batch norm features", 16, []Layer{NewDenseLayer(16, 8, ReLU{}), NewBatchNormLayer(6), NewDenseLayer(8, 2, Identity{})}},
+	}
+	for _, c := range cases {
+		s := NewSequential(c.inputs, 0.1, nil, c.layers...)
+		if _, err := s.Fit(X, Y, 1); err == nil {
+			t.Errorf("%s: Fit expected an error", c.name)
+		}
+		if _, err := s.Predict(X); err == nil {
+			t.Errorf("%s: Predict expected an error", c.name)
+		}
+	}
+
+	// A kernel that only fits with the padding is valid
+	s := NewSequential(16, 0.1, nil, NewConv2D(4, 4, 1, 2, 5, 1, 1, nil), NewFlatten(), NewDenseLayer(2*2*2, 2, Identity{}))
+	if _, err := s.Fit(X, Y, 1); err != nil {
+		t.Errorf("kernel fitting the padded image: %v", err)
+	}
+}